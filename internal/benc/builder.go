@@ -0,0 +1,410 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Implements the reverse direction of torrent.go: assembling a .torrent file
+// out of a local file or directory tree, rather than parsing one.
+
+package benc
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/eduardo-antunes/torrent-go/internal/merkle"
+)
+
+// Piece length used when a Builder does not specify one
+const defaultPieceLength = 256 * 1024
+
+// Number of piece-hashing goroutines used when a Builder does not specify one
+const defaultWorkers = 4
+
+// Builder assembles a .torrent file from a local file or directory tree. It
+// streams file contents through a worker pool while hashing pieces, so that
+// multi-gigabyte inputs remain practical to create. The zero value is not
+// usable; construct one with NewBuilder
+type Builder struct {
+	Announce     string
+	AnnounceList [][]string // BEP 12 tiers of trackers
+	Comment      string
+	CreatedBy    string
+	CreationDate int64
+	Private      bool
+	Source       string
+	WebSeeds     []string // BEP 19 "url-list"
+
+	PieceLength int  // in bytes; defaults to 256 KiB if zero
+	V2          bool // also build a BEP 52 Merkle tree per file
+	Workers     int  // piece-hashing goroutines; defaults to 4 if zero
+
+	name  string
+	isDir bool
+	files []builderFile
+}
+
+// builderFile is one file collected under a Builder's root. path is relative
+// to the torrent's name, as it will appear in the info dict
+type builderFile struct {
+	path   []string
+	abs    string
+	length int64
+}
+
+// NewBuilder collects the file or directory tree rooted at path, ready to be
+// hashed by Build. The torrent's name defaults to the base name of path
+func NewBuilder(path string) (*Builder, error) {
+	path = filepath.Clean(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Could not stat %s\n%w", path, err)
+	}
+	b := &Builder{name: filepath.Base(path)}
+	if !info.IsDir() {
+		b.files = []builderFile{{path: []string{b.name}, abs: path, length: info.Size()}}
+		return b, nil
+	}
+
+	b.isDir = true
+	err = filepath.Walk(path, func(abs string, fi os.FileInfo, walkErr error) error {
+		if walkErr != nil || fi.IsDir() {
+			return walkErr
+		}
+		rel, err := filepath.Rel(path, abs)
+		if err != nil {
+			return err
+		}
+		b.files = append(b.files, builderFile{
+			path:   strings.Split(filepath.ToSlash(rel), "/"),
+			abs:    abs,
+			length: fi.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[!] Could not walk %s\n%w", path, err)
+	}
+	if len(b.files) == 0 {
+		return nil, fmt.Errorf("[!] %s has no files to hash\n", path)
+	}
+	return b, nil
+}
+
+// Build hashes the collected files and assembles the resulting Torrent. The
+// returned Torrent can be written out with Encode, or more directly with
+// Write or WriteToFile
+func (b *Builder) Build() (*Torrent, error) {
+	pieceLen := b.PieceLength
+	if pieceLen <= 0 {
+		pieceLen = defaultPieceLength
+	}
+
+	pieces, err := b.hashPiecesV1(pieceLen)
+	if err != nil {
+		return nil, err
+	}
+	info := map[string]any{"name": b.name, "piece length": pieceLen, "pieces": pieces}
+	if b.Private {
+		info["private"] = 1
+	}
+	if b.Source != "" {
+		info["source"] = b.Source
+	}
+	if !b.isDir {
+		info["length"] = int(b.files[0].length)
+	} else {
+		files := make([]any, len(b.files))
+		for i, f := range b.files {
+			files[i] = map[string]any{"path": toAnySlice(f.path), "length": int(f.length)}
+		}
+		info["files"] = files
+	}
+
+	if b.V2 {
+		trees, err := b.hashFilesV2()
+		if err != nil {
+			return nil, err
+		}
+		info["meta version"] = 2
+		info["file tree"] = buildFileTree(b.files, trees)
+	}
+
+	dict := map[string]any{"info": info}
+	if b.Announce != "" {
+		dict["announce"] = b.Announce
+	}
+	if len(b.AnnounceList) > 0 {
+		tiers := make([]any, len(b.AnnounceList))
+		for i, tier := range b.AnnounceList {
+			tiers[i] = toAnySlice(tier)
+		}
+		dict["announce-list"] = tiers
+	}
+	if b.Comment != "" {
+		dict["comment"] = b.Comment
+	}
+	if b.CreatedBy != "" {
+		dict["created by"] = b.CreatedBy
+	}
+	if b.CreationDate != 0 {
+		dict["creation date"] = int(b.CreationDate)
+	}
+	if len(b.WebSeeds) > 0 {
+		dict["url-list"] = toAnySlice(b.WebSeeds)
+	}
+
+	// Round-tripping through the parser, rather than populating a Torrent by
+	// hand, guarantees that a built torrent is validated exactly like one
+	// that was read off disk, and gives it a raw dict to re-encode from
+	enc, err := encodeDict(dict)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTorrent(enc)
+}
+
+// Write builds the torrent and bencodes it to w
+func (b *Builder) Write(w io.Writer) error {
+	torrent, err := b.Build()
+	if err != nil {
+		return err
+	}
+	return torrent.Encode(w)
+}
+
+// WriteToFile builds the torrent and writes it to the named file
+func (b *Builder) WriteToFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("[!] Could not create %s\n%w", path, err)
+	}
+	defer file.Close()
+	return b.Write(file)
+}
+
+// pieceJob is one pieceLen-sized chunk of the concatenated files, along with
+// its position among the other pieces
+type pieceJob struct {
+	index int
+	data  []byte
+}
+
+// hashPiecesV1 streams the concatenated contents of b.files through a pool
+// of b.Workers goroutines, returning the concatenated SHA-1 hash of each
+// pieceLen-sized piece, as described by BEP 3
+func (b *Builder) hashPiecesV1(pieceLen int) ([]byte, error) {
+	var total int64
+	for _, f := range b.files {
+		total += f.length
+	}
+	pieceCount := int((total + int64(pieceLen) - 1) / int64(pieceLen))
+
+	workers := b.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	jobs := make(chan pieceJob)
+	results := make(chan pieceJob)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				sum := sha1.Sum(job.data)
+				results <- pieceJob{index: job.index, data: sum[:]}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	readErrCh := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		readErrCh <- b.streamPieces(pieceLen, jobs)
+	}()
+
+	pieces := make([][]byte, pieceCount)
+	for res := range results {
+		pieces[res.index] = res.data
+	}
+	if err := <-readErrCh; err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, pieceCount*sha1.Size)
+	for _, p := range pieces {
+		out = append(out, p...)
+	}
+	return out, nil
+}
+
+// streamPieces reads through b.files in order, splitting their concatenated
+// contents into pieceLen-sized chunks and sending one job per chunk
+func (b *Builder) streamPieces(pieceLen int, jobs chan<- pieceJob) error {
+	buf := make([]byte, pieceLen)
+	filled, index := 0, 0
+	flush := func() {
+		if filled == 0 {
+			return
+		}
+		chunk := make([]byte, filled)
+		copy(chunk, buf[:filled])
+		jobs <- pieceJob{index: index, data: chunk}
+		index++
+		filled = 0
+	}
+	for _, f := range b.files {
+		file, err := os.Open(f.abs)
+		if err != nil {
+			return fmt.Errorf("[!] Could not open %s\n%w", f.abs, err)
+		}
+		for {
+			n, err := file.Read(buf[filled:])
+			filled += n
+			if filled == pieceLen {
+				flush()
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				file.Close()
+				return fmt.Errorf("[!] Could not read %s\n%w", f.abs, err)
+			}
+		}
+		file.Close()
+	}
+	flush()
+	return nil
+}
+
+// hashFilesV2 builds a BEP 52 Merkle tree over each file's 16 KiB leaves,
+// concurrently across b.Workers goroutines
+func (b *Builder) hashFilesV2() ([]merkle.Tree, error) {
+	workers := b.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	type result struct {
+		index int
+		tree  merkle.Tree
+		err   error
+	}
+	jobs := make(chan int)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				tree, err := hashFileV2(b.files[index])
+				results <- result{index: index, tree: tree, err: err}
+			}
+		}()
+	}
+	go func() {
+		for i := range b.files {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	trees := make([]merkle.Tree, len(b.files))
+	var firstErr error
+	for res := range results {
+		if res.err != nil && firstErr == nil {
+			firstErr = res.err
+		}
+		trees[res.index] = res.tree
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return trees, nil
+}
+
+// hashFileV2 streams f's contents in 16 KiB leaves and builds the Merkle
+// tree over them
+func hashFileV2(f builderFile) (merkle.Tree, error) {
+	file, err := os.Open(f.abs)
+	if err != nil {
+		return merkle.Tree{}, fmt.Errorf("[!] Could not open %s\n%w", f.abs, err)
+	}
+	defer file.Close()
+
+	var leaves [][32]byte
+	buf := make([]byte, merkle.LeafSize)
+	for {
+		n, err := io.ReadFull(file, buf)
+		if n > 0 {
+			leaves = append(leaves, sha256.Sum256(buf[:n]))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return merkle.Tree{}, fmt.Errorf("[!] Could not read %s\n%w", f.abs, err)
+		}
+	}
+	return merkle.BuildFromLeaves(leaves), nil
+}
+
+// buildFileTree assembles a BEP 52 "file tree" dict out of the given files
+// and their already-computed Merkle trees, inverting parseFileTree
+func buildFileTree(files []builderFile, trees []merkle.Tree) map[string]any {
+	tree := make(map[string]any)
+	for i, f := range files {
+		node := tree
+		for _, part := range f.path[:len(f.path)-1] {
+			next, ok := node[part].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				node[part] = next
+			}
+			node = next
+		}
+		leaf := f.path[len(f.path)-1]
+		root := trees[i].Root
+		node[leaf] = map[string]any{
+			"": map[string]any{"length": int(f.length), "pieces root": root[:]},
+		}
+	}
+	return tree
+}
+
+func toAnySlice(s []string) []any {
+	out := make([]any, len(s))
+	for i, v := range s {
+		out[i] = v
+	}
+	return out
+}
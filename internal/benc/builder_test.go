@@ -0,0 +1,91 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package benc
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuilderSingleFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hello.txt")
+	content := bytes.Repeat([]byte("x"), 100)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	b, err := NewBuilder(path)
+	if err != nil {
+		t.Fatalf("NewBuilder failed: %v", err)
+	}
+	b.PieceLength = 40 // force 3 pieces: 40, 40, 20
+	b.Announce = "http://tracker.example/announce"
+
+	torrent, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if !torrent.IsV1Only() {
+		t.Fatalf("expected a v1-only torrent")
+	}
+	if torrent.Announce != b.Announce {
+		t.Fatalf("Announce = %q, want %q", torrent.Announce, b.Announce)
+	}
+	files := torrent.Files()
+	if len(files) != 1 || files[0].Length != uint64(len(content)) {
+		t.Fatalf("Files() = %v, want a single hello.txt entry of length %d", files, len(content))
+	}
+
+	want := sha1.Sum(content[:40])
+	got := torrent.Info.Pieces[:sha1.Size]
+	if !bytes.Equal(got, want[:]) {
+		t.Fatalf("first piece hash = %x, want %x", got, want)
+	}
+}
+
+func TestBuilderEncodeRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hello.txt")
+	if err := os.WriteFile(path, []byte("hello, torrent"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	b, err := NewBuilder(path)
+	if err != nil {
+		t.Fatalf("NewBuilder failed: %v", err)
+	}
+	torrent, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := torrent.Encode(&buf); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	reparsed, err := ParseTorrent(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseTorrent of encoded torrent failed: %v", err)
+	}
+	if reparsed.InfoHash != torrent.InfoHash {
+		t.Fatalf("InfoHash changed across Encode round trip: %x != %x", reparsed.InfoHash, torrent.InfoHash)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// A small reflection-based decoder that fills in a struct from a value
+// produced by the parser in this package (a map[string]any, []any, []byte or
+// int), matching dict keys against a field's `bencode` tag, or its lowercased
+// name if no tag is given. A tag of "-" leaves the field for the caller to
+// fill in separately, as torrent.go does for InfoBytes and response.go does
+// for Peers.
+
+package benc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// decode fills out, which must be a non-nil pointer to a struct, from raw.
+// Since the parser reports strings as []byte to stay binary-safe, fields
+// that are genuinely text (Announce, Comment, ...) are declared as string
+// and get converted back by decode; fields that are genuinely binary
+// (Pieces, ...) should be declared []byte themselves, and are copied as-is
+func decode(raw, out any) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("[!] decode target must be a non-nil pointer, got %T", out)
+	}
+	return decodeValue(v.Elem(), raw)
+}
+
+func decodeValue(field reflect.Value, raw any) error {
+	switch field.Kind() {
+	case reflect.Struct:
+		dict, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("[!] expected a dictionary, got %T", raw)
+		}
+		return decodeStruct(field, dict)
+	case reflect.Slice:
+		return decodeSlice(field, raw)
+	case reflect.String:
+		b, ok := raw.([]byte)
+		if !ok {
+			return fmt.Errorf("[!] expected a string, got %T", raw)
+		}
+		field.SetString(string(b))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := raw.(int)
+		if !ok {
+			return fmt.Errorf("[!] expected an integer, got %T", raw)
+		}
+		field.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := raw.(int)
+		if !ok {
+			return fmt.Errorf("[!] expected an integer, got %T", raw)
+		}
+		field.SetUint(uint64(n))
+	default:
+		return fmt.Errorf("[!] cannot decode into field of type %s", field.Type())
+	}
+	return nil
+}
+
+// decodeStruct fills the exported fields of v from dict, skipping any field
+// tagged "-" or missing from dict entirely (which is left at its zero value)
+func decodeStruct(v reflect.Value, dict map[string]any) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("bencode")
+		if tag == "-" {
+			continue
+		}
+		key := tag
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		raw, ok := dict[key]
+		if !ok {
+			continue
+		}
+		if err := decodeValue(v.Field(i), raw); err != nil {
+			return fmt.Errorf("[!] field %q: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// decodeSlice fills field, a slice, from raw: a []byte directly for a
+// []byte field, otherwise a []any decoded element by element
+func decodeSlice(field reflect.Value, raw any) error {
+	if field.Type().Elem().Kind() == reflect.Uint8 {
+		b, ok := raw.([]byte)
+		if !ok {
+			return fmt.Errorf("[!] expected a byte string, got %T", raw)
+		}
+		field.SetBytes(append([]byte(nil), b...))
+		return nil
+	}
+	vals, ok := raw.([]any)
+	if !ok {
+		return fmt.Errorf("[!] expected a list, got %T", raw)
+	}
+	slice := reflect.MakeSlice(field.Type(), len(vals), len(vals))
+	for i, val := range vals {
+		if err := decodeValue(slice.Index(i), val); err != nil {
+			return err
+		}
+	}
+	field.Set(slice)
+	return nil
+}
@@ -19,54 +19,91 @@
 package benc
 
 import (
+	"bytes"
 	"fmt"
-	"strings"
+	"sort"
+	"strconv"
 )
 
-// Encodes strings: <length>:<text>
-func encodeStr(text string) string {
-	return fmt.Sprintf("%d:%s", len(text), text)
+// Encodes byte strings: <length>:<text>
+func encodeStr(buf *bytes.Buffer, text []byte) {
+	buf.WriteString(strconv.Itoa(len(text)))
+	buf.WriteByte(':')
+	buf.Write(text)
 }
 
 // Encodes integers: i<num>e
-func encodeInt(num int) string {
-	return fmt.Sprintf("i%de", num)
+func encodeInt(buf *bytes.Buffer, num int) {
+	buf.WriteByte('i')
+	buf.WriteString(strconv.Itoa(num))
+	buf.WriteByte('e')
 }
 
 // Encodes lists: l(<value>)*e
-func encodeList(vals []any) string {
-	var build strings.Builder
-	build.WriteByte('l')
+func encodeList(buf *bytes.Buffer, vals []any) error {
+	buf.WriteByte('l')
 	for _, val := range vals {
-		build.WriteString(encodeVal(val))
+		if err := encodeVal(buf, val); err != nil {
+			return err
+		}
 	}
-	build.WriteByte('e')
-	return build.String()
+	buf.WriteByte('e')
+	return nil
 }
 
-// Encodes dicts: d(<key><value>)*e
-func encodeDict(dict map[string]any) string {
-	var build strings.Builder
-	build.WriteByte('d')
-	for key, val := range dict {
-		build.WriteString(encodeStr(key))
-		build.WriteString(encodeVal(val))
+// EncodeDict bencodes a dict of ints, strings, []byte, lists and nested
+// dicts, in lexicographic key order. It's exported so that other packages
+// that need to build ad-hoc bencoded messages, such as the BEP 10 extension
+// handshake, can reuse this encoder instead of rolling their own
+func EncodeDict(dict map[string]any) ([]byte, error) {
+	return encodeDict(dict)
+}
+
+// Encodes dicts: d(<key><value>)*e. Keys are emitted in lexicographic byte
+// order, as required by BEP 3, so that the encoding of a given dict is
+// deterministic: this matters because info dicts are hashed with SHA-1 to
+// derive the infohash, and map iteration order in Go is randomized
+func encodeDict(dict map[string]any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('d')
+	keys := make([]string, 0, len(dict))
+	for key := range dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		encodeStr(&buf, []byte(key))
+		if err := encodeVal(&buf, dict[key]); err != nil {
+			return nil, err
+		}
 	}
-	build.WriteByte('e')
-	return build.String()
+	buf.WriteByte('e')
+	return buf.Bytes(), nil
 }
 
-// General encoding function (don't use outside of this file)
-func encodeVal(val any) string {
+// General encoding function (don't use outside of this file). val must be
+// one of the types the parser itself produces (int, []byte, map[string]any,
+// []any) or a plain string; anything else is a caller bug, reported as an
+// error rather than a panic, so that a future field of an unconverted type
+// fails with a clear message instead of crashing the process
+func encodeVal(buf *bytes.Buffer, val any) error {
 	switch v := val.(type) {
 	case int:
-		return encodeInt(v)
+		encodeInt(buf, v)
 	case string:
-		return encodeStr(v)
+		encodeStr(buf, []byte(v))
+	case []byte:
+		encodeStr(buf, v)
 	case []any:
-		return encodeList(v)
+		return encodeList(buf, v)
 	case map[string]any:
-		return encodeDict(v)
+		encoded, err := encodeDict(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	default:
+		return fmt.Errorf("[!] Cannot bencode value of type %T\n", val)
 	}
-	panic("What?!!!") // should never happen
+	return nil
 }
@@ -0,0 +1,244 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Magnet URIs (BEP 9, with the BEP 53 extensions) let a torrent be
+// identified and fetched without ever handing out a .torrent file: they
+// carry just an infohash, a display name and a handful of trackers, leaving
+// the info dict to be fetched from peers.
+
+package benc
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	magnetHashPrefix   = "urn:btih:" // v1 (BEP 3) infohash, SHA-1
+	magnetHashV2Prefix = "urn:btmh:" // v2 (BEP 52) infohash, a SHA-256 multihash
+	multihashSha256    = 0x12        // multihash function code for sha2-256
+	multihashSha256Len = 0x20        // multihash digest length for sha2-256
+)
+
+// FileRange is one inclusive range of file indices from the BEP 53 "so"
+// parameter, such as "4-8"; Start == End represents a single selected index
+type FileRange struct {
+	Start, End int
+}
+
+// Magnet is the parsed form of a magnet URI: a torrent's identity and a few
+// hints for fetching it, without the info dict itself
+type Magnet struct {
+	InfoHash   [20]byte // v1 infohash (SHA-1); valid only if HasV1
+	InfoHashV2 [32]byte // v2 infohash (SHA-256); valid only if HasV2
+	HasV1      bool
+	HasV2      bool
+
+	DisplayName   string      // dn
+	Trackers      []string    // tr, in the order given
+	WebSeeds      []string    // xs, exact source URLs (usually just one)
+	AcceptSources []string    // as, acceptable source fallback URLs
+	Peers         []PeerAddr  // x.pe
+	Select        []FileRange // so, the BEP 53 file-select indices
+}
+
+// ParseMagnet parses a magnet URI, extracting its infohash(es) (at least one
+// of xt=urn:btih:... or xt=urn:btmh:1220... is required) along with its
+// display name, trackers and the rest of the BEP 53 parameters. The info
+// dict itself isn't carried by the magnet link; fetch it from peers instead
+func ParseMagnet(uri string) (*Magnet, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Malformed magnet URI\n%w", err)
+	}
+	if u.Scheme != "magnet" {
+		return nil, fmt.Errorf("[!] Not a magnet URI: %s\n", uri)
+	}
+	query := u.Query()
+
+	m := new(Magnet)
+	for _, xt := range query["xt"] {
+		switch {
+		case strings.HasPrefix(xt, magnetHashPrefix):
+			m.InfoHash, err = parseInfoHash(xt)
+			if err != nil {
+				return nil, err
+			}
+			m.HasV1 = true
+		case strings.HasPrefix(xt, magnetHashV2Prefix):
+			m.InfoHashV2, err = parseInfoHashV2(xt)
+			if err != nil {
+				return nil, err
+			}
+			m.HasV2 = true
+		default:
+			return nil, fmt.Errorf("[!] Unsupported magnet urn: %s\n", xt)
+		}
+	}
+	if !m.HasV1 && !m.HasV2 {
+		return nil, fmt.Errorf("[!] Magnet URI has no xt infohash\n")
+	}
+
+	m.DisplayName = query.Get("dn")
+	m.Trackers = query["tr"]
+	m.WebSeeds = query["xs"]
+	m.AcceptSources = query["as"]
+
+	for _, pe := range query["x.pe"] {
+		addr, err := netip.ParseAddrPort(pe)
+		if err != nil {
+			return nil, fmt.Errorf("[!] Invalid x.pe peer address %q in magnet URI\n%w", pe, err)
+		}
+		m.Peers = append(m.Peers, PeerAddr{addr})
+	}
+
+	if so := query.Get("so"); so != "" {
+		m.Select, err = parseSelect(so)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// String reassembles m into a magnet URI
+func (m *Magnet) String() string {
+	var params []string
+	if m.HasV1 {
+		params = append(params, "xt="+magnetHashPrefix+hex.EncodeToString(m.InfoHash[:]))
+	}
+	if m.HasV2 {
+		multihash := append([]byte{multihashSha256, multihashSha256Len}, m.InfoHashV2[:]...)
+		params = append(params, "xt="+magnetHashV2Prefix+hex.EncodeToString(multihash))
+	}
+	if m.DisplayName != "" {
+		params = append(params, "dn="+url.QueryEscape(m.DisplayName))
+	}
+	for _, tr := range m.Trackers {
+		params = append(params, "tr="+url.QueryEscape(tr))
+	}
+	for _, xs := range m.WebSeeds {
+		params = append(params, "xs="+url.QueryEscape(xs))
+	}
+	for _, as := range m.AcceptSources {
+		params = append(params, "as="+url.QueryEscape(as))
+	}
+	for _, peer := range m.Peers {
+		params = append(params, "x.pe="+peer.String())
+	}
+	if len(m.Select) > 0 {
+		params = append(params, "so="+formatSelect(m.Select))
+	}
+	return "magnet:?" + strings.Join(params, "&")
+}
+
+// Magnet builds a magnet link out of a parsed torrent: its infohash(es),
+// name, trackers and web seeds. Peer addresses and a file selection are
+// left unset, since those aren't carried by a .torrent file
+func (t *Torrent) Magnet() *Magnet {
+	m := &Magnet{
+		DisplayName: t.Info.Name,
+		HasV1:       t.hasV1,
+		InfoHash:    t.InfoHash,
+		HasV2:       t.hasV2,
+		InfoHashV2:  t.InfoHashV2,
+		WebSeeds:    t.WebSeeds,
+	}
+	if len(t.AnnounceList) > 0 {
+		for _, tier := range t.AnnounceList {
+			m.Trackers = append(m.Trackers, tier...)
+		}
+	} else if t.Announce != "" {
+		m.Trackers = []string{t.Announce}
+	}
+	return m
+}
+
+// Parses the xt parameter of a magnet URI, extracting a v1 infohash from
+// either a 40-char hex or a 32-char base32 btih urn
+func parseInfoHash(xt string) ([20]byte, error) {
+	var hash [20]byte
+	encoded := xt[len(magnetHashPrefix):]
+	var raw []byte
+	var err error
+	switch len(encoded) {
+	case 40:
+		raw, err = hex.DecodeString(encoded)
+	case 32:
+		raw, err = base32.StdEncoding.DecodeString(strings.ToUpper(encoded))
+	default:
+		return hash, fmt.Errorf("[!] Invalid infohash length in magnet URI: %s\n", encoded)
+	}
+	if err != nil || len(raw) != 20 {
+		return hash, fmt.Errorf("[!] Invalid infohash in magnet URI: %s\n", encoded)
+	}
+	copy(hash[:], raw)
+	return hash, nil
+}
+
+// Parses the xt parameter of a magnet URI, extracting a v2 infohash from a
+// hex-encoded sha2-256 multihash btmh urn, as described by BEP 52
+func parseInfoHashV2(xt string) ([32]byte, error) {
+	var hash [32]byte
+	encoded := xt[len(magnetHashV2Prefix):]
+	raw, err := hex.DecodeString(encoded)
+	if err != nil || len(raw) != 2+32 || raw[0] != multihashSha256 || raw[1] != multihashSha256Len {
+		return hash, fmt.Errorf("[!] Invalid v2 infohash multihash in magnet URI: %s\n", encoded)
+	}
+	copy(hash[:], raw[2:])
+	return hash, nil
+}
+
+// Parses the so parameter of a magnet URI: a comma-separated list of file
+// indices and inclusive ranges, e.g. "0,2,4-8", as described by BEP 53
+func parseSelect(so string) ([]FileRange, error) {
+	var ranges []FileRange
+	for _, tok := range strings.Split(so, ",") {
+		if dash := strings.IndexByte(tok, '-'); dash >= 0 {
+			start, err1 := strconv.Atoi(tok[:dash])
+			end, err2 := strconv.Atoi(tok[dash+1:])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("[!] Invalid file range %q in so parameter\n", tok)
+			}
+			ranges = append(ranges, FileRange{Start: start, End: end})
+			continue
+		}
+		n, err := strconv.Atoi(tok)
+		if err != nil {
+			return nil, fmt.Errorf("[!] Invalid file index %q in so parameter\n", tok)
+		}
+		ranges = append(ranges, FileRange{Start: n, End: n})
+	}
+	return ranges, nil
+}
+
+// Formats ranges back into the so parameter's comma-separated form
+func formatSelect(ranges []FileRange) string {
+	toks := make([]string, len(ranges))
+	for i, r := range ranges {
+		if r.Start == r.End {
+			toks[i] = strconv.Itoa(r.Start)
+		} else {
+			toks[i] = fmt.Sprintf("%d-%d", r.Start, r.End)
+		}
+	}
+	return strings.Join(toks, ",")
+}
@@ -14,7 +14,7 @@
  *  limitations under the License.
  */
 
-/* The BitTorrent protocol makes use of a small data markup language called
+/* The torrent protocol makes use of a small data markup language called
  * 'bencoding' by the official spec. Here, I will call it B-encoding. It
  * determines a standard text representation for strings, integers, lists and
  * dictionaries. In a nutshell:
@@ -25,10 +25,14 @@
  * Dictionary keys must be strings, all numbers must be represented in base 10
  * and aren't supposed to be 0-prefixed.
  *
- * This file implements a simple parser for B-encoding.
+ * This file implements a simple parser for B-encoding. B-encoded strings are
+ * not necessarily text (torrent files embed raw SHA-1 hashes in the pieces
+ * field, among other things), so they are parsed as []byte rather than
+ * string; callers that know a given field is actually text convert it
+ * themselves.
  */
 
-package metainfo
+package benc
 
 import (
 	"bytes"
@@ -48,11 +52,6 @@ func (err *parseError) Error() string {
 Relevant portion of the text: %s`, err.pos, err.reason, err.context)
 }
 
-// "Union" type for generic B-encoded values
-type value struct {
-
-}
-
 type parser struct {
 	enc []byte // B-encoded text
 	i   int    // current position in the encoded string
@@ -102,26 +101,34 @@ func fromAscii(ascii []byte, n int) (int, bool) {
 	return num, true
 }
 
-// Parses strings: <length>:<text>
-func (p *parser) parseStr() (string, error) {
+// Parses strings: <length>:<text>. Returned as raw bytes, since B-encoded
+// strings are really byte strings and not necessarily text
+func (p *parser) parseStr() ([]byte, error) {
 	i := bytes.IndexByte(p.enc[p.i:], ':')
 	// no ':' in the text => invalid string
 	if i < 0 {
-		return "", p.err("invalid string", -1)
-    }
+		return nil, p.err("invalid string", -1)
+	}
 	n, ok := fromAscii(p.enc[p.i:], i)
 	// length isn't properly specified => invalid string
 	if !ok {
-		return "", p.err("invalid string length specifier", -1)
+		return nil, p.err("invalid string length specifier", -1)
 	}
 	p.i += i + 1
-	text := string(p.enc[p.i : p.i+n])
+	// declared length runs past the end of the input => invalid string
+	if n < 0 || p.i+n > len(p.enc) {
+		return nil, p.err("string runs past end of input", -1)
+	}
+	text := p.enc[p.i : p.i+n]
 	p.i += n // advances the parser to the next token
-	return text, nil
+	return append([]byte(nil), text...), nil
 }
 
 // Parses integers: i<num>e
 func (p *parser) parseInt() (int, error) {
+	if p.i >= len(p.enc) {
+		return 0, p.err("unexpected end of input", -1)
+	}
 	if p.enc[p.i] != 'i' {
 		return 0, p.err("invalid integer", -1)
 	}
@@ -141,6 +148,9 @@ func (p *parser) parseInt() (int, error) {
 
 // Parses lists: l(<value>*)e
 func (p *parser) parseList() ([]any, error) {
+	if p.i >= len(p.enc) {
+		return nil, p.err("unexpected end of input", -1)
+	}
 	if p.enc[p.i] != 'l' {
 		return nil, p.err("invalid list", -1)
 	}
@@ -167,8 +177,12 @@ func (p *parser) parseList() ([]any, error) {
 	return vals, nil
 }
 
-// Parses dicts: d(<key><value>)*e
+// Parses dicts: d(<key><value>)*e. Keys are always decoded as strings, since
+// the spec requires it, even though the underlying bytes are parsed as []byte
 func (p *parser) parseDict() (map[string]any, error) {
+	if p.i >= len(p.enc) {
+		return nil, p.err("unexpected end of input", -1)
+	}
 	if p.enc[p.i] != 'd' {
 		return nil, p.err("invalid dictionary", -1)
 	}
@@ -180,7 +194,7 @@ func (p *parser) parseDict() (map[string]any, error) {
 		return nil, p.errDelim("d", start)
 	}
 	for p.enc[p.i] != 'e' {
-		key, err := p.parseStr() // keys must be strings
+		rawKey, err := p.parseStr() // keys must be strings
 		if err != nil {
 			// invalid key => invalid dict
 			return nil, err
@@ -190,7 +204,7 @@ func (p *parser) parseDict() (map[string]any, error) {
 			// invalid value => invalid dict
 			return nil, err
 		}
-		dict[key] = val
+		dict[string(rawKey)] = val
 		if p.i >= len(p.enc) {
 			// missing terminating 'e' => unbalanced delimiter error
 			return nil, p.errDelim("d", start)
@@ -200,8 +214,70 @@ func (p *parser) parseDict() (map[string]any, error) {
 	return dict, nil
 }
 
+// Parses dicts like parseDict, but additionally reports the [start, end)
+// byte range within p.enc of the value of each key named in capture, found
+// directly in this dict (not recursively). This lets callers recover the
+// exact original bytes of a sub-value, such as a torrent's info dict, rather
+// than relying on a re-encoding of its parsed form to be byte-identical
+func (p *parser) parseDictCapture(capture ...string) (map[string]any, map[string][2]int, error) {
+	if p.i >= len(p.enc) {
+		return nil, nil, p.err("unexpected end of input", -1)
+	}
+	if p.enc[p.i] != 'd' {
+		return nil, nil, p.err("invalid dictionary", -1)
+	}
+	start := p.i
+	p.i++ // advances past the 'd'
+	dict := make(map[string]any)
+	spans := make(map[string][2]int)
+	if p.i >= len(p.enc) {
+		return nil, nil, p.errDelim("d", start)
+	}
+	for p.enc[p.i] != 'e' {
+		rawKey, err := p.parseStr()
+		if err != nil {
+			return nil, nil, err
+		}
+		valStart := p.i
+		val, err := p.parseVal()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := string(rawKey)
+		dict[key] = val
+		for _, wanted := range capture {
+			if wanted == key {
+				spans[key] = [2]int{valStart, p.i}
+			}
+		}
+		if p.i >= len(p.enc) {
+			return nil, nil, p.errDelim("d", start)
+		}
+	}
+	p.i++ // advances past the 'e'
+	return dict, spans, nil
+}
+
+// DecodeDict parses a single bencoded dictionary from the front of enc and
+// reports how many bytes of enc it consumed. It's exported so that other
+// packages that need to decode a standalone dict out of a larger byte
+// stream, such as a BEP 9 ut_metadata message (which appends a raw data
+// chunk right after the header dict), can reuse this parser instead of
+// rolling their own
+func DecodeDict(enc []byte) (map[string]any, int, error) {
+	p := newParser(enc)
+	dict, err := p.parseDict()
+	if err != nil {
+		return nil, 0, err
+	}
+	return dict, p.i, nil
+}
+
 // General parsing method (don't use outside of this file)
 func (p *parser) parseVal() (any, error) {
+	if p.i >= len(p.enc) {
+		return nil, p.err("unexpected end of input", -1)
+	}
 	// Check type id character
 	switch p.enc[p.i] {
 	case 'd':
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package benc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	want := map[string]any{
+		"announce": []byte("http://tracker.example/announce"),
+		"info": map[string]any{
+			"name":         []byte("file.bin"),
+			"length":       12345,
+			"piece length": 16384,
+			"pieces":       []byte{0x01, 0x02, 0x03, 0xff, 0x00, 0xaa}, // not valid UTF-8 on purpose
+		},
+		"list": []any{1, []byte("two"), 3},
+	}
+	enc, err := encodeDict(want)
+	if err != nil {
+		t.Fatalf("encodeDict failed: %v", err)
+	}
+
+	p := newParser(enc)
+	got, err := p.parseDict()
+	if err != nil {
+		t.Fatalf("parseDict failed on encodeDict's own output: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip mismatch:\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestEncodeDictKeyOrder(t *testing.T) {
+	// BEP 3 requires keys in lexicographic byte order; map iteration in Go
+	// is randomized, so this also guards against encodeDict regressing to
+	// non-deterministic output
+	dict := map[string]any{"zebra": 1, "apple": 2, "mango": 3}
+	want := "d5:applei2e5:mangoi3e5:zebrai1ee"
+	for i := 0; i < 20; i++ {
+		enc, err := encodeDict(dict)
+		if err != nil {
+			t.Fatalf("encodeDict failed: %v", err)
+		}
+		if got := string(enc); got != want {
+			t.Fatalf("encodeDict(%v) = %q, want %q", dict, got, want)
+		}
+	}
+}
+
+func TestEncodeDictUnsupportedType(t *testing.T) {
+	// a bool isn't one of the types the parser itself ever produces, nor one
+	// encodeVal special-cases; it must be reported as an error rather than
+	// panicking, so a future field of an unconverted type fails cleanly
+	if _, err := encodeDict(map[string]any{"flag": true}); err == nil {
+		t.Fatalf("encodeDict succeeded on an unsupported value type, want an error")
+	}
+}
+
+func TestParseStrNegativeLength(t *testing.T) {
+	// a '-' isn't a digit, so fromAscii must reject it outright rather than
+	// strconv.Atoi-style negative-number parsing, which would otherwise let
+	// a negative length through into a negative-length slice expression
+	p := newParser([]byte("-5:short"))
+	if _, err := p.parseStr(); err == nil {
+		t.Fatalf("parseStr succeeded on a negative-length string, want an error")
+	}
+}
+
+func TestParseStrTruncated(t *testing.T) {
+	// the string claims to be 100 bytes long but only 5 remain: parsing it
+	// must return an error rather than panic on an out-of-range slice
+	p := newParser([]byte("100:short"))
+	if _, err := p.parseStr(); err == nil {
+		t.Fatalf("parseStr succeeded on a truncated string, want an error")
+	}
+}
+
+func TestParseDictTruncatedValue(t *testing.T) {
+	_, err := newParser([]byte("d4:infod6:pieces40:" + "too short" + "ee")).parseDict()
+	if err == nil {
+		t.Fatalf("parseDict succeeded on a dict with a truncated string value, want an error")
+	}
+}
+
+func TestParseEmptyInput(t *testing.T) {
+	// an empty input has no type id character for parseVal to switch on; every
+	// entry point must report an error instead of indexing p.enc out of range
+	if _, err := newParser(nil).parseVal(); err == nil {
+		t.Fatalf("parseVal succeeded on empty input, want an error")
+	}
+	if _, err := newParser(nil).parseInt(); err == nil {
+		t.Fatalf("parseInt succeeded on empty input, want an error")
+	}
+	if _, err := newParser(nil).parseList(); err == nil {
+		t.Fatalf("parseList succeeded on empty input, want an error")
+	}
+	if _, err := newParser(nil).parseDict(); err == nil {
+		t.Fatalf("parseDict succeeded on empty input, want an error")
+	}
+	if _, _, err := newParser(nil).parseDictCapture("info"); err == nil {
+		t.Fatalf("parseDictCapture succeeded on empty input, want an error")
+	}
+	if _, err := ParseTorrent(nil); err == nil {
+		t.Fatalf("ParseTorrent succeeded on empty input, want an error")
+	}
+}
+
+func TestParseMidTokenTruncatedInput(t *testing.T) {
+	// each of these ends right after a delimiter that promises more to come,
+	// with nothing left in p.enc for the next token: this must not panic
+	cases := []string{
+		"d4:info", // dict cut off right after a key, no value follows
+		"l",       // list cut off right after the 'l', no element follows
+		"i",       // integer cut off right after the 'i', no digits follow
+	}
+	for _, enc := range cases {
+		if _, err := ParseTorrent([]byte(enc)); err == nil {
+			t.Fatalf("ParseTorrent(%q) succeeded on truncated input, want an error", enc)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Decodes the peer lists handed out by trackers, in both the compact form
+// of BEP 23 (IPv4) / BEP 7 (IPv6) and the older, non-compact dictionary form.
+
+package benc
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// PeerAddr identifies a peer by its address, as given out by a tracker
+type PeerAddr struct {
+	netip.AddrPort
+}
+
+const (
+	compactV4Len = 6  // 4-byte IPv4 address + 2-byte port
+	compactV6Len = 18 // 16-byte IPv6 address + 2-byte port
+)
+
+// DecodeCompactPeers decodes a BEP 23 compact peer list: a flat run of
+// 6-byte records, each a 4-byte IPv4 address followed by a big-endian port
+func DecodeCompactPeers(raw []byte) ([]PeerAddr, error) {
+	return decodeCompactPeers(raw, compactV4Len)
+}
+
+// DecodeCompactPeers6 decodes a BEP 7 compact IPv6 peer list: a flat run of
+// 18-byte records, each a 16-byte IPv6 address followed by a big-endian port
+func DecodeCompactPeers6(raw []byte) ([]PeerAddr, error) {
+	return decodeCompactPeers(raw, compactV6Len)
+}
+
+func decodeCompactPeers(raw []byte, recordLen int) ([]PeerAddr, error) {
+	if len(raw)%recordLen != 0 {
+		return nil, fmt.Errorf("[!] Compact peer list length %d is not a multiple of %d\n", len(raw), recordLen)
+	}
+	peers := make([]PeerAddr, 0, len(raw)/recordLen)
+	for i := 0; i < len(raw); i += recordLen {
+		record := raw[i : i+recordLen]
+		addr, ok := netip.AddrFromSlice(record[:recordLen-2])
+		if !ok {
+			return nil, fmt.Errorf("[!] Invalid peer address in compact peer list\n")
+		}
+		port := uint16(record[recordLen-2])<<8 | uint16(record[recordLen-1])
+		peers = append(peers, PeerAddr{netip.AddrPortFrom(addr, port)})
+	}
+	return peers, nil
+}
+
+// decodeDictPeers decodes the older, non-compact peer list: a bencoded list
+// of dicts, each with "ip" and "port" entries (and, optionally, "peer id",
+// which isn't needed here)
+func decodeDictPeers(raw []any) ([]PeerAddr, error) {
+	peers := make([]PeerAddr, 0, len(raw))
+	for _, entry := range raw {
+		dict, ok := entry.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("[!] Invalid entry in non-compact peer list\n")
+		}
+		rawIp, _ := dict["ip"].([]byte)
+		port, _ := dict["port"].(int)
+		addr, err := netip.ParseAddr(string(rawIp))
+		if err != nil {
+			return nil, fmt.Errorf("[!] Invalid peer IP %q in non-compact peer list\n%w", rawIp, err)
+		}
+		peers = append(peers, PeerAddr{netip.AddrPortFrom(addr, uint16(port))})
+	}
+	return peers, nil
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package benc
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestDecodeCompactPeers(t *testing.T) {
+	raw := []byte{192, 168, 0, 1, 0x1A, 0xE1, 10, 0, 0, 2, 0x00, 0x50}
+	peers, err := DecodeCompactPeers(raw)
+	if err != nil {
+		t.Fatalf("DecodeCompactPeers failed: %v", err)
+	}
+	want := []PeerAddr{
+		{netip.MustParseAddrPort("192.168.0.1:6881")},
+		{netip.MustParseAddrPort("10.0.0.2:80")},
+	}
+	if len(peers) != len(want) || peers[0] != want[0] || peers[1] != want[1] {
+		t.Fatalf("DecodeCompactPeers(%v) = %v, want %v", raw, peers, want)
+	}
+}
+
+func TestDecodeCompactPeersBadLength(t *testing.T) {
+	if _, err := DecodeCompactPeers([]byte{1, 2, 3}); err == nil {
+		t.Fatal("expected an error for a compact peer list with a partial record")
+	}
+}
+
+func TestParseTrackerResponseNonCompact(t *testing.T) {
+	resp := "d8:intervali1800e5:peersl" +
+		"d2:ip9:127.0.0.17:peer id20:aaaaaaaaaaaaaaaaaaaa4:porti6881ee" +
+		"ee"
+	tr, err := ParseTrackerResponse([]byte(resp))
+	if err != nil {
+		t.Fatalf("ParseTrackerResponse failed: %v", err)
+	}
+	if tr.Interval != 1800 {
+		t.Fatalf("Interval = %d, want 1800", tr.Interval)
+	}
+	want := PeerAddr{netip.MustParseAddrPort("127.0.0.1:6881")}
+	if len(tr.Peers) != 1 || tr.Peers[0] != want {
+		t.Fatalf("Peers = %v, want [%v]", tr.Peers, want)
+	}
+}
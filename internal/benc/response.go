@@ -16,17 +16,13 @@
 
 package benc
 
-import (
-	"fmt"
-
-	"github.com/mitchellh/mapstructure"
-)
+import "fmt"
 
 type TrackerResponse struct {
-	interval    int    // interval, in seconds, between tracker requests
-	minInterval int    // minimum value for the interval
-	trackerId   string // tracker ID
-	Peers       string // connected peers in a compact model
+	Interval    int        `bencode:"interval"`     // interval, in seconds, between tracker requests
+	MinInterval int        `bencode:"min interval"` // minimum value for the interval
+	TrackerId   string     `bencode:"tracker id"`   // tracker ID
+	Peers       []PeerAddr `bencode:"-"`            // filled in separately by parsePeers
 }
 
 func ParseTrackerResponse(responseContents []byte) (*TrackerResponse, error) {
@@ -40,8 +36,42 @@ func ParseTrackerResponse(responseContents []byte) (*TrackerResponse, error) {
 	}
 
 	resp := new(TrackerResponse)
-	if err = mapstructure.Decode(rawResp, resp); err != nil {
+	if err = decode(rawResp, resp); err != nil {
 		return nil, fmt.Errorf("[!] Malformed response from tracker\n%w", err)
 	}
+
+	peers, err := parsePeers(rawResp)
+	if err != nil {
+		return nil, err
+	}
+	resp.Peers = peers
 	return resp, nil
 }
+
+// Parses the peers and peers6 fields, which may each be either a compact
+// blob (BEP 23 / BEP 7) or, for peers, a non-compact list of dicts
+func parsePeers(rawResp map[string]any) ([]PeerAddr, error) {
+	var peers []PeerAddr
+	switch raw := rawResp["peers"].(type) {
+	case []byte:
+		v4, err := DecodeCompactPeers(raw)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, v4...)
+	case []any:
+		dictPeers, err := decodeDictPeers(raw)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, dictPeers...)
+	}
+	if raw, ok := rawResp["peers6"].([]byte); ok {
+		v6, err := DecodeCompactPeers6(raw)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, v6...)
+	}
+	return peers, nil
+}
@@ -16,67 +16,177 @@
 
 // While the parser component of this package translates B-encoded text into
 // unstructured dictionaries, this file translates those dictionaries into
-// more useful data structures. It also verifies the validity of torrent files.
-// Of course, most of the heavy lifting is handled by the excellent
-// mapstructure library.
+// more useful data structures, via decode and its bencode struct tags. It
+// also verifies the validity of torrent files.
+//
+// Both BEP 3 (v1) and BEP 52 (v2) torrents are supported, including hybrid
+// torrents that carry both an info dict compatible with v1 clients and the
+// v2 "file tree"/"meta version" fields, as a transition aid.
 
 package benc
 
 import (
+	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
 	"fmt"
-
-	"github.com/mitchellh/mapstructure"
+	"io"
+	"sort"
+	"strings"
 )
 
 // Torrent metainfo structure, parsed from .torrent files
 type Torrent struct {
-	InfoHash     [20]byte // hash of the info field (used as torrent ID)
-	Announce     string   // announce URL, for the tracker
-	CreationDate int64    `mapstructure:"creation date"`
-	CreatedBy    string   `mapstructure:"created by"`
+	Announce     string
+	AnnounceList [][]string `bencode:"announce-list"` // BEP 12 tiers of trackers
+	CreationDate int64      `bencode:"creation date"`
+	CreatedBy    string     `bencode:"created by"`
 	Comment      string
+	WebSeeds     []string `bencode:"url-list"` // BEP 19 web seed URLs
+
+	// Info is the v1 (BEP 3) info field: Pieces and Files/Length are left at
+	// their zero value for a v2-only torrent
+	Info Info `bencode:"-"`
+
+	InfoHash   [20]byte // v1 infohash (SHA-1 of InfoBytes); zero if v2-only
+	InfoHashV2 [32]byte // v2 infohash (SHA-256 of InfoBytes); zero if v1-only
+
+	// InfoBytes holds the exact bytes of the info dict as they appeared in
+	// the original torrent, rather than a re-encoding of its parsed form.
+	// Hashing these exact bytes, instead of re-encoding the parsed dict, is
+	// what keeps InfoHash/InfoHashV2 correct even for torrents whose info
+	// dict has non-canonical key ordering or otherwise wouldn't round-trip
+	// through encodeDict byte-for-byte. External code that needs to forward
+	// the info dict as-is, such as BEP 9 metadata exchange, can also rely on it
+	InfoBytes []byte
+
+	hasV1   bool
+	hasV2   bool
+	filesV2 []fileV2 // v2 only: flattened "file tree"
+
+	raw map[string]any // the parsed dict this torrent came from, if any
+}
 
-	// File information; two modes of representation
-	singleFileMode bool           // is there only one file?
-	singleInfo     singleFileInfo // info field in single-file mode
-	multiInfo      multiFileInfo  // info field in multi-file mode
+// Info is the parsed form of a v1 (BEP 3) info dict: piece hashes and a file
+// list, normalized across single-file mode (where Length is set and Files
+// is empty) and multi-file mode (the other way around). Use UpvertedFiles
+// to read the file list without caring which mode produced it
+type Info struct {
+	Name        string
+	Length      uint64     // only set in single-file mode
+	PieceLength uint64     `bencode:"piece length"`
+	Pieces      []byte     // concatenated SHA-1 hashes
+	Files       []FileInfo // only set in multi-file mode
 }
 
-// Representation of a file in multi-file mode
-type file struct {
+// FileInfo describes one file within a torrent: its length and its path,
+// relative to the torrent's Name. It's reported the same way regardless of
+// whether the torrent is v1, v2 or hybrid
+type FileInfo struct {
 	Path   []string
 	Length uint64
 }
 
-// Torrent info field in single-file mode
-type singleFileInfo struct {
-	Name        string
-	Pieces      string
-	PieceLength uint64 `mapstructure:"piece length"`
-	Length      uint64
+// Piece describes one entry of a v1 piece hash list: its SHA-1 hash, and its
+// offset and length within the concatenation of all of the torrent's files
+type Piece struct {
+	Hash   [20]byte
+	Offset uint64
+	Length uint64
 }
 
-// Torrent info field in multi-file mode
-type multiFileInfo struct {
-	Name        string
-	Pieces      string
-	PieceLength uint64 `mapstructure:"piece length"`
-	Files       []file
+// A single file entry flattened out of a v2 "file tree" dict, as described
+// by BEP 52
+type fileV2 struct {
+	Path       []string
+	Length     uint64
+	PiecesRoot [32]byte // Merkle root over the file's 16 KiB leaves
+}
+
+// TotalLength returns the combined length of every file described by info,
+// regardless of single- or multi-file mode
+func (info *Info) TotalLength() int64 {
+	if len(info.Files) == 0 {
+		return int64(info.Length)
+	}
+	var total int64
+	for _, f := range info.Files {
+		total += int64(f.Length)
+	}
+	return total
+}
+
+// NumPieces reports how many SHA-1 piece hashes info.Pieces holds
+func (info *Info) NumPieces() int {
+	return len(info.Pieces) / sha1.Size
+}
+
+// Piece returns the hash, offset and length of the i-th piece, accounting
+// for the torrent's final piece usually being shorter than PieceLength
+func (info *Info) Piece(i int) Piece {
+	offset := uint64(i) * info.PieceLength
+	length := info.PieceLength
+	if remaining := uint64(info.TotalLength()) - offset; remaining < length {
+		length = remaining
+	}
+	var hash [20]byte
+	copy(hash[:], info.Pieces[i*sha1.Size:(i+1)*sha1.Size])
+	return Piece{Hash: hash, Offset: offset, Length: length}
+}
+
+// UpvertedFiles returns info's files uniformly across single- and
+// multi-file mode, so that callers such as piece verification and storage
+// backends don't need to branch on which mode produced it
+func (info *Info) UpvertedFiles() []FileInfo {
+	if len(info.Files) != 0 {
+		return info.Files
+	}
+	return []FileInfo{{Path: []string{info.Name}, Length: info.Length}}
+}
+
+// IsV1Only reports whether the torrent carries only a v1 (BEP 3) info dict
+func (t *Torrent) IsV1Only() bool { return t.hasV1 && !t.hasV2 }
+
+// IsV2Only reports whether the torrent carries only a v2 (BEP 52) info dict
+func (t *Torrent) IsV2Only() bool { return t.hasV2 && !t.hasV1 }
+
+// IsHybrid reports whether the torrent carries both a v1 and a v2 info dict
+// describing the same content, as recommended by BEP 52 during the
+// transition away from v1
+func (t *Torrent) IsHybrid() bool { return t.hasV1 && t.hasV2 }
+
+// HasInfo reports whether t's info dict has actually been parsed, as opposed
+// to t being a placeholder built from a magnet URI (BEP 9) whose info dict is
+// still to be fetched from peers
+func (t *Torrent) HasInfo() bool { return t.hasV1 || t.hasV2 }
+
+// Files returns every file in the torrent, uniformly across v1 and v2. For a
+// hybrid torrent, the v2 file list is reported, since it describes the same
+// content as the v1 one
+func (t *Torrent) Files() []FileInfo {
+	if t.hasV2 {
+		files := make([]FileInfo, len(t.filesV2))
+		for i, f := range t.filesV2 {
+			files[i] = FileInfo{Path: f.Path, Length: f.Length}
+		}
+		return files
+	}
+	return t.Info.UpvertedFiles()
 }
 
 // Parse metainfo structure from the contents of a standard torrent file
 func ParseTorrent(fileContents []byte) (*Torrent, error) {
-	// Parse the torrent to get a raw dictionary
+	// Parse the torrent to get a raw dictionary, also capturing the exact
+	// byte range of the info field so its hash doesn't depend on re-encoding
 	p := newParser(fileContents)
-	rawMetainfo, err := p.parseDict()
+	rawMetainfo, spans, err := p.parseDictCapture("info")
 	if err != nil {
 		return nil, err // bad torrent file
 	}
 	// Validate all non-info fields of the raw dictionary, filling the torrent
 	// structure with their values or triggering an error
 	torrent := new(Torrent)
-	if err = mapstructure.Decode(rawMetainfo, torrent); err != nil {
+	if err = decode(rawMetainfo, torrent); err != nil {
 		return nil, fmt.Errorf("[!] Malformed torrent file\n%w", err)
 	}
 	// Tedious validation code for the info field
@@ -88,25 +198,114 @@ func ParseTorrent(fileContents []byte) (*Torrent, error) {
 	if !ok {
 		return nil, fmt.Errorf("[!] Info field must be a dictionary\n")
 	}
-	// The info field must be handled specially, as its structure will be
-	// different in single-file mode and multi-file mode. We detect the mode
-	// by checking for info.files
-	if _, filesExists := info["files"]; filesExists {
-		// Multi-file mode torrent
-		torrent.singleFileMode = false
-		if err = mapstructure.Decode(info, &torrent.multiInfo); err != nil {
-			return nil, fmt.Errorf("[!] Malformed multi-file info field\n%w", err)
+	infoSpan := spans["info"]
+	torrent.InfoBytes = append([]byte(nil), fileContents[infoSpan[0]:infoSpan[1]]...)
+
+	if err = decode(info, &torrent.Info); err != nil {
+		return nil, fmt.Errorf("[!] Malformed info field\n%w", err)
+	}
+	if _, hasPieces := info["pieces"]; hasPieces {
+		torrent.hasV1 = true
+		torrent.InfoHash = sha1.Sum(torrent.InfoBytes)
+	}
+
+	if metaVersion, _ := info["meta version"].(int); metaVersion == 2 {
+		rawTree, ok := info["file tree"].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("[!] v2 torrent is missing its file tree\n")
 		}
-	} else {
-		// Single-file mode torrent
-		torrent.singleFileMode = true
-		if err = mapstructure.Decode(info, &torrent.singleInfo); err != nil {
-			return nil, fmt.Errorf("[!] Malformed multi-file info field\n%w", err)
+		files, err := parseFileTree(rawTree, nil)
+		if err != nil {
+			return nil, err
 		}
+		torrent.hasV2 = true
+		torrent.filesV2 = files
+		torrent.InfoHashV2 = sha256.Sum256(torrent.InfoBytes)
+	}
+	if !torrent.hasV1 && !torrent.hasV2 {
+		return nil, fmt.Errorf("[!] Info field has neither v1 pieces nor a v2 file tree\n")
 	}
-	// Some metainfo fields (notably InfoHash) are not directly present in the
-	// torrent file, but must instead be computed from it
-	bencInfo := encodeDict(info)
-	torrent.InfoHash = sha1.Sum([]byte(bencInfo))
+	torrent.raw = rawMetainfo
 	return torrent, nil
 }
+
+// Encode writes the torrent back out as a standard bencoded .torrent file.
+// The info field is spliced in verbatim from t.InfoBytes rather than
+// re-encoded from t.raw, so the infohash (and thus the torrent's identity to
+// other clients) is unaffected by the round trip even when the original
+// info dict wasn't in canonical key order
+func (t *Torrent) Encode(w io.Writer) error {
+	dict := t.raw
+	if dict == nil {
+		return fmt.Errorf("[!] Torrent has no dictionary to encode\n")
+	}
+	enc, err := encodeDictWithRawInfo(dict, t.InfoBytes)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(enc)
+	return err
+}
+
+// encodeDictWithRawInfo behaves like encodeDict, except that its "info" key,
+// if present, is written out as infoBytes verbatim instead of being
+// re-encoded from dict["info"]
+func encodeDictWithRawInfo(dict map[string]any, infoBytes []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('d')
+	keys := make([]string, 0, len(dict))
+	for key := range dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		encodeStr(&buf, []byte(key))
+		if key == "info" {
+			buf.Write(infoBytes)
+			continue
+		}
+		if err := encodeVal(&buf, dict[key]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('e')
+	return buf.Bytes(), nil
+}
+
+// Recursively flattens a BEP 52 "file tree" dict into a flat list of files.
+// Each leaf in the tree is a one-entry dict, keyed by the empty string,
+// giving the file's length and the root of the Merkle tree over its 16 KiB
+// leaves; anything else is an intermediate directory to recurse into
+func parseFileTree(tree map[string]any, prefix []string) ([]fileV2, error) {
+	var files []fileV2
+	for name, rawNode := range tree {
+		node, ok := rawNode.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("[!] Malformed file tree entry %q\n", name)
+		}
+		path := append(append([]string{}, prefix...), name)
+		if leaf, isLeaf := node[""].(map[string]any); isLeaf {
+			var entry struct {
+				Length     uint64
+				PiecesRoot []byte `bencode:"pieces root"`
+			}
+			if err := decode(leaf, &entry); err != nil {
+				return nil, fmt.Errorf("[!] Malformed file tree leaf %q\n%w", strings.Join(path, "/"), err)
+			}
+			var root [32]byte
+			copy(root[:], entry.PiecesRoot)
+			files = append(files, fileV2{Path: path, Length: entry.Length, PiecesRoot: root})
+			continue
+		}
+		sub, err := parseFileTree(node, path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sub...)
+	}
+	// Map iteration order is randomized; sort for a deterministic file list
+	sort.Slice(files, func(i, j int) bool {
+		return strings.Join(files[i].Path, "/") < strings.Join(files[j].Path, "/")
+	})
+	return files, nil
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package benc
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestParseTorrentV2(t *testing.T) {
+	root := bytes.Repeat([]byte{0xCD}, 32)
+	// a single-file v2 torrent: file tree { "a.txt": { "": { length, pieces root } } }
+	infoDict := "d" +
+		"9:file treed5:a.txtd0:d6:lengthi9e11:pieces root" + lenPrefixed(root) + "eee" +
+		"12:meta versioni2e" +
+		"4:name10:v2.torrent" +
+		"12:piece lengthi16384e" +
+		"e"
+	torrentFile := "d" + "8:announce4:test" + "4:info" + infoDict + "e"
+
+	torrent, err := ParseTorrent([]byte(torrentFile))
+	if err != nil {
+		t.Fatalf("ParseTorrent failed: %v", err)
+	}
+	if !torrent.IsV2Only() {
+		t.Fatalf("expected a v2-only torrent, got v1=%v v2=%v", torrent.hasV1, torrent.hasV2)
+	}
+	if want := sha256.Sum256([]byte(infoDict)); torrent.InfoHashV2 != want {
+		t.Fatalf("InfoHashV2 = %x, want %x", torrent.InfoHashV2, want)
+	}
+	files := torrent.Files()
+	if len(files) != 1 || files[0].Length != 9 || files[0].Path[len(files[0].Path)-1] != "a.txt" {
+		t.Fatalf("Files() = %v, want a single a.txt entry of length 9", files)
+	}
+}
+
+// lenPrefixed bencodes a raw byte string as <len>:<bytes>
+func lenPrefixed(b []byte) string {
+	return string(encodeStrBytes(b))
+}
+
+func encodeStrBytes(b []byte) []byte {
+	var buf bytes.Buffer
+	encodeStr(&buf, b)
+	return buf.Bytes()
+}
+
+func TestParseTorrentInfoHashNonCanonicalOrder(t *testing.T) {
+	// Keys out of lexicographic order: re-encoding this dict with encodeDict
+	// would sort them back and silently change the hashed bytes, so InfoHash
+	// must be derived from InfoBytes, the untouched original bytes, instead
+	pieces := bytes.Repeat([]byte{0xAB}, 20)
+	infoDict := "d" +
+		"6:pieces" + lenPrefixed(pieces) +
+		"12:piece lengthi16384e" +
+		"6:lengthi4e" +
+		"4:name8:file.bin" +
+		"e"
+	torrentFile := "d" + "8:announce4:test" + "4:info" + infoDict + "e"
+
+	torrent, err := ParseTorrent([]byte(torrentFile))
+	if err != nil {
+		t.Fatalf("ParseTorrent failed: %v", err)
+	}
+	if string(torrent.InfoBytes) != infoDict {
+		t.Fatalf("InfoBytes = %q, want %q", torrent.InfoBytes, infoDict)
+	}
+	if want := sha1.Sum([]byte(infoDict)); torrent.InfoHash != want {
+		t.Fatalf("InfoHash = %x, want %x", torrent.InfoHash, want)
+	}
+}
+
+func TestEncodeNonCanonicalOrderPreservesInfoHash(t *testing.T) {
+	// keys out of lexicographic order: re-encoding the info dict from its
+	// parsed form, rather than splicing InfoBytes back in verbatim, would
+	// sort them back and change the bytes, so a round trip through Encode
+	// must leave the infohash unaffected
+	pieces := bytes.Repeat([]byte{0xAB}, 20)
+	infoDict := "d" +
+		"6:pieces" + lenPrefixed(pieces) +
+		"12:piece lengthi16384e" +
+		"6:lengthi4e" +
+		"4:name8:file.bin" +
+		"e"
+	torrentFile := "d" + "8:announce4:test" + "4:info" + infoDict + "e"
+
+	torrent, err := ParseTorrent([]byte(torrentFile))
+	if err != nil {
+		t.Fatalf("ParseTorrent failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := torrent.Encode(&buf); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	reencoded, err := ParseTorrent(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseTorrent of encoded torrent failed: %v", err)
+	}
+	if reencoded.InfoHash != torrent.InfoHash {
+		t.Fatalf("InfoHash changed across Encode round trip: %x != %x", reencoded.InfoHash, torrent.InfoHash)
+	}
+	if !bytes.Equal(reencoded.InfoBytes, []byte(infoDict)) {
+		t.Fatalf("Encode round trip did not preserve info bytes verbatim:\ngot:  %q\nwant: %q",
+			reencoded.InfoBytes, infoDict)
+	}
+}
+
+func TestInfoPieceAndUpvertedFiles(t *testing.T) {
+	hash1 := bytes.Repeat([]byte{0x11}, sha1.Size)
+	hash2 := bytes.Repeat([]byte{0x22}, sha1.Size)
+	info := Info{
+		Name:        "single.bin",
+		Length:      30,
+		PieceLength: 20,
+		Pieces:      append(append([]byte{}, hash1...), hash2...),
+	}
+
+	if got := info.TotalLength(); got != 30 {
+		t.Fatalf("TotalLength() = %d, want 30", got)
+	}
+	if got := info.NumPieces(); got != 2 {
+		t.Fatalf("NumPieces() = %d, want 2", got)
+	}
+	if p := info.Piece(0); p.Offset != 0 || p.Length != 20 || !bytes.Equal(p.Hash[:], hash1) {
+		t.Fatalf("Piece(0) = %+v, want offset 0 length 20 hash %x", p, hash1)
+	}
+	if p := info.Piece(1); p.Offset != 20 || p.Length != 10 || !bytes.Equal(p.Hash[:], hash2) {
+		t.Fatalf("Piece(1) = %+v, want offset 20 length 10 (short last piece) hash %x", p, hash2)
+	}
+
+	files := info.UpvertedFiles()
+	if len(files) != 1 || files[0].Path[0] != "single.bin" || files[0].Length != 30 {
+		t.Fatalf("UpvertedFiles() = %v, want a single synthetic single.bin entry", files)
+	}
+
+	info.Length, info.Files = 0, []FileInfo{{Path: []string{"a"}, Length: 1}, {Path: []string{"b"}, Length: 2}}
+	if got := info.TotalLength(); got != 3 {
+		t.Fatalf("TotalLength() with Files set = %d, want 3", got)
+	}
+	if files := info.UpvertedFiles(); len(files) != 2 {
+		t.Fatalf("UpvertedFiles() with Files set = %v, want the Files slice itself", files)
+	}
+}
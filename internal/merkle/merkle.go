@@ -0,0 +1,93 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Package merkle builds the per-file Merkle trees that BEP 52 (BitTorrent
+// v2) uses in place of v1's flat list of piece hashes: every file is hashed
+// in 16 KiB leaves, the last layer is padded with zero-hashes up to the next
+// power of two, and each layer is hashed pairwise up to a single root.
+package merkle
+
+import "crypto/sha256"
+
+// LeafSize is the fixed size of a Merkle tree leaf, as mandated by BEP 52
+const LeafSize = 16 * 1024
+
+// zeroHash is the SHA-256 hash of a LeafSize block of zero bytes, used to
+// pad the leaf layer up to a power of two
+var zeroHash = sha256.Sum256(make([]byte, LeafSize))
+
+// Tree is a Merkle tree over a file's 16 KiB leaves. Layers[0] holds the
+// (padded) leaf hashes and Layers[len(Layers)-1] holds just the root, so
+// that a verifier can walk back down from the root to check any leaf
+type Tree struct {
+	Root   [32]byte
+	Layers [][][32]byte
+}
+
+// Build hashes data into 16 KiB leaves and builds the Merkle tree over them
+func Build(data []byte) Tree {
+	leaves := make([][32]byte, 0, (len(data)+LeafSize-1)/LeafSize)
+	for i := 0; i < len(data); i += LeafSize {
+		end := i + LeafSize
+		if end > len(data) {
+			end = len(data)
+		}
+		leaves = append(leaves, sha256.Sum256(data[i:end]))
+	}
+	return BuildFromLeaves(leaves)
+}
+
+// BuildFromLeaves builds a Merkle tree from already-hashed 16 KiB leaves,
+// for callers that only have the leaf hashes (such as BEP 52's piece layers)
+// rather than the raw file data
+func BuildFromLeaves(leaves [][32]byte) Tree {
+	n := nextPow2(len(leaves))
+	layer := make([][32]byte, n)
+	copy(layer, leaves)
+	for i := len(leaves); i < n; i++ {
+		layer[i] = zeroHash
+	}
+	layers := [][][32]byte{layer}
+	for len(layer) > 1 {
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = hashPair(layer[2*i], layer[2*i+1])
+		}
+		layers = append(layers, next)
+		layer = next
+	}
+	return Tree{Root: layer[0], Layers: layers}
+}
+
+func hashPair(a, b [32]byte) [32]byte {
+	var buf [64]byte
+	copy(buf[:32], a[:])
+	copy(buf[32:], b[:])
+	return sha256.Sum256(buf[:])
+}
+
+// nextPow2 returns the smallest power of two that is >= n, treating 0 as 1
+// (an empty file still has a single, zero-hash leaf, as required by BEP 52)
+func nextPow2(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
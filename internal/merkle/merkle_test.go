@@ -0,0 +1,56 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestBuildSingleLeaf(t *testing.T) {
+	data := make([]byte, LeafSize)
+	tree := Build(data)
+	want := sha256.Sum256(data)
+	if tree.Root != want {
+		t.Fatalf("Root = %x, want %x", tree.Root, want)
+	}
+	if len(tree.Layers) != 1 {
+		t.Fatalf("len(Layers) = %d, want 1", len(tree.Layers))
+	}
+}
+
+func TestBuildPadsToPowerOfTwo(t *testing.T) {
+	// 3 leaves should be padded up to 4, with the 4th a zero-hash
+	data := make([]byte, 3*LeafSize)
+	for i := range data {
+		data[i] = 0xAB
+	}
+	tree := Build(data)
+	if len(tree.Layers[0]) != 4 {
+		t.Fatalf("len(Layers[0]) = %d, want 4", len(tree.Layers[0]))
+	}
+	if tree.Layers[0][3] != zeroHash {
+		t.Fatalf("Layers[0][3] = %x, want the zero-hash %x", tree.Layers[0][3], zeroHash)
+	}
+	// root must be the hash of the two second-layer nodes
+	left := hashPair(tree.Layers[0][0], tree.Layers[0][1])
+	right := hashPair(tree.Layers[0][2], tree.Layers[0][3])
+	want := hashPair(left, right)
+	if tree.Root != want {
+		t.Fatalf("Root = %x, want %x", tree.Root, want)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Implements the ut_metadata extension (BEP 9), which lets the info dict of
+// a torrent be fetched straight from peers instead of a .torrent file, atop
+// the BEP 10 extension protocol handshake.
+
+package peer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/eduardo-antunes/torrent-go/internal/benc"
+)
+
+const metadataPieceSize = 16 * 1024 // 16 KiB, as fixed by BEP 9
+
+// ut_metadata message types, as carried by the "msg_type" field of the
+// bencoded header that precedes each message
+const (
+	metadataRequest = 0
+	metadataData    = 1
+	metadataReject  = 2
+)
+
+// extensionHandshake is the bencoded dict sent right after the base
+// handshake to negotiate support for extensions, as described by BEP 10
+type extensionHandshake struct {
+	metadataExtId int // id the peer wants ut_metadata messages tagged with
+	metadataSize  int // total size of the info dict, in bytes
+}
+
+// FetchInfoFromPeers retrieves the info dict for magnet, a partially
+// populated Torrent built from a magnet URI (see ParseMagnet), by requesting
+// it piece by piece from peers over the BEP 10/BEP 9 extension protocol. The
+// reassembled info dict is SHA-1 verified against magnet.InfoHash before
+// being parsed, so a successful return is guaranteed to match the magnet.
+// The returned Torrent keeps magnet's trackers and web seeds, since those
+// aren't part of the info dict itself
+func FetchInfoFromPeers(ctx context.Context, magnet *benc.Torrent, peers []benc.PeerAddr) (*benc.Torrent, error) {
+	var lastErr error
+	for _, addr := range peers {
+		full, err := fetchInfoFromPeer(ctx, magnet.InfoHash, addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		full.Announce = magnet.Announce
+		full.AnnounceList = magnet.AnnounceList
+		full.WebSeeds = magnet.WebSeeds
+		return full, nil
+	}
+	return nil, fmt.Errorf("[!] Could not fetch info dict from any peer\n%w", lastErr)
+}
+
+// Runs the whole exchange with a single peer: base handshake, extension
+// handshake, and then requesting every metadata piece in turn
+func fetchInfoFromPeer(ctx context.Context, infoHash [20]byte, addr benc.PeerAddr) (*benc.Torrent, error) {
+	conn, err := Dial(ctx, addr, infoHash)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ext, err := handshakeExtensions(conn)
+	if err != nil {
+		return nil, err
+	}
+	if ext.metadataExtId == 0 {
+		return nil, fmt.Errorf("[!] Peer %s does not support ut_metadata\n", addr)
+	}
+
+	numPieces := (ext.metadataSize + metadataPieceSize - 1) / metadataPieceSize
+	raw := make([]byte, 0, ext.metadataSize)
+	for i := 0; i < numPieces; i++ {
+		chunk, err := requestMetadataPiece(conn, ext.metadataExtId, i)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, chunk...)
+	}
+	if sha1.Sum(raw) != infoHash {
+		return nil, fmt.Errorf("[!] Metadata from peer %s does not match the magnet infohash\n", addr)
+	}
+	return benc.ParseTorrent(wrapInfoDict(raw))
+}
+
+// Sends our own extension handshake and reads the peer's, extracting the
+// message id it wants ut_metadata tagged with and the declared metadata size
+func handshakeExtensions(conn *Conn) (extensionHandshake, error) {
+	ours := map[string]any{
+		"m": map[string]any{"ut_metadata": localMetadataExtId},
+	}
+	encoded, err := benc.EncodeDict(ours)
+	if err != nil {
+		return extensionHandshake{}, err
+	}
+	if err := conn.WriteMessage(msgExtended, append([]byte{0}, encoded...)); err != nil {
+		return extensionHandshake{}, err
+	}
+	id, payload, err := conn.ReadMessage()
+	if err != nil {
+		return extensionHandshake{}, err
+	}
+	if id != msgExtended || len(payload) == 0 || payload[0] != 0 {
+		return extensionHandshake{}, fmt.Errorf("[!] Peer did not answer with an extension handshake\n")
+	}
+	dict, _, err := benc.DecodeDict(payload[1:])
+	if err != nil {
+		return extensionHandshake{}, fmt.Errorf("[!] Malformed extension handshake\n%w", err)
+	}
+	m, _ := dict["m"].(map[string]any)
+	extId, _ := m["ut_metadata"].(int)
+	size, _ := dict["metadata_size"].(int)
+	return extensionHandshake{metadataExtId: extId, metadataSize: size}, nil
+}
+
+// The message id we ask peers to tag their ut_metadata messages with; fixed
+// since we only ever speak to one peer per connection
+const localMetadataExtId = 1
+
+// Requests a single metadata piece and waits for it, following the
+// msg_type request/data/reject exchange of BEP 9
+func requestMetadataPiece(conn *Conn, extId int, piece int) ([]byte, error) {
+	req := map[string]any{"msg_type": metadataRequest, "piece": piece}
+	encoded, err := benc.EncodeDict(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.WriteMessage(msgExtended, append([]byte{byte(extId)}, encoded...)); err != nil {
+		return nil, err
+	}
+	id, payload, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	if id != msgExtended || len(payload) == 0 {
+		return nil, fmt.Errorf("[!] Peer sent an unexpected reply to a metadata request\n")
+	}
+	dict, n, err := benc.DecodeDict(payload[1:])
+	if err != nil {
+		return nil, fmt.Errorf("[!] Malformed metadata message\n%w", err)
+	}
+	msgType, _ := dict["msg_type"].(int)
+	switch msgType {
+	case metadataReject:
+		return nil, fmt.Errorf("[!] Peer rejected metadata piece %d\n", piece)
+	case metadataData:
+		return payload[1+n:], nil
+	}
+	return nil, fmt.Errorf("[!] Peer sent unexpected msg_type %d\n", msgType)
+}
+
+// Wraps a raw, already-validated info dict back into a one-field metainfo
+// dict, so that it can be fed through the existing bencode decoding pipeline
+// in benc.ParseTorrent
+func wrapInfoDict(rawInfo []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("d4:info")
+	buf.Write(rawInfo)
+	buf.WriteByte('e')
+	return buf.Bytes()
+}
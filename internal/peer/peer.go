@@ -0,0 +1,158 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Package peer implements the client side of the BitTorrent peer wire
+// protocol: the base handshake from BEP 3, the length-prefixed message
+// framing it introduces, and the BEP 10 extension protocol that later BEPs
+// (such as ut_metadata, BEP 9) build on top of.
+package peer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/eduardo-antunes/torrent-go/internal/benc"
+)
+
+const protocolName = "BitTorrent protocol"
+
+// extensionReserved marks support for the BEP 10 extension protocol in the
+// reserved bytes of the handshake
+var extensionReserved = [8]byte{0, 0, 0, 0, 0, 0x10, 0, 0}
+
+// Conn is a TCP connection to a peer, past the initial handshake
+type Conn struct {
+	net.Conn
+	PeerId [20]byte
+}
+
+// Dial reaches out to a peer and performs the base handshake described by
+// BEP 3, advertising support for the BEP 10 extension protocol
+func Dial(ctx context.Context, addr benc.PeerAddr, infoHash [20]byte) (*Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr.String())
+	if err != nil {
+		return nil, fmt.Errorf("[!] Could not reach peer %s\n%w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := writeHandshake(conn, infoHash); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	peerId, err := readHandshake(conn, infoHash)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{Conn: conn, PeerId: peerId}, nil
+}
+
+func writeHandshake(conn net.Conn, infoHash [20]byte) error {
+	var buf bytes.Buffer
+	buf.WriteByte(byte(len(protocolName)))
+	buf.WriteString(protocolName)
+	buf.Write(extensionReserved[:])
+	buf.Write(infoHash[:])
+	buf.Write(localPeerId())
+	_, err := conn.Write(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("[!] Could not send handshake to peer\n%w", err)
+	}
+	return nil
+}
+
+func readHandshake(conn net.Conn, infoHash [20]byte) ([20]byte, error) {
+	var peerId [20]byte
+	head := make([]byte, 1)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return peerId, fmt.Errorf("[!] Could not read handshake from peer\n%w", err)
+	}
+	rest := make([]byte, int(head[0])+48)
+	if _, err := io.ReadFull(conn, rest); err != nil {
+		return peerId, fmt.Errorf("[!] Could not read handshake from peer\n%w", err)
+	}
+	pstrLen := int(head[0])
+	theirHash := rest[pstrLen+8 : pstrLen+28]
+	if !bytes.Equal(theirHash, infoHash[:]) {
+		return peerId, fmt.Errorf("[!] Peer answered handshake with a different infohash\n")
+	}
+	copy(peerId[:], rest[pstrLen+28:pstrLen+48])
+	return peerId, nil
+}
+
+// Generates a fresh, semi-random peer id for a one-off connection such as a
+// metadata exchange
+func localPeerId() []byte {
+	id := make([]byte, 20)
+	n := copy(id, "-TG1000-")
+	rand.Read(id[n:])
+	return id
+}
+
+// Message ids for the subset of the base wire protocol this package cares
+// about; most of it (choke, have, bitfield, piece, ...) belongs to the
+// download engine and isn't implemented here
+const msgExtended = 20
+
+// maxMessageSize caps how large a single message's declared length may be,
+// before any of it is read. Nothing this client speaks (the base handshake's
+// framed messages, or BEP 10 extension messages such as ut_metadata) comes
+// anywhere close to this; without the cap, a peer could claim a length near
+// the 4-byte prefix's 0xFFFFFFFF ceiling and force a multi-GB allocation
+// with nothing else required
+const maxMessageSize = 256 * 1024 // 256 KiB
+
+// ReadMessage reads one length-prefixed message from the peer, blocking
+// until it arrives. A zero-length message (a keep-alive) is reported as id
+// -1 with no payload
+func (c *Conn) ReadMessage() (id int8, payload []byte, err error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(c, lenBuf[:]); err != nil {
+		return 0, nil, fmt.Errorf("[!] Could not read message length from peer\n%w", err)
+	}
+	length := binary.BigEndian.Uint32(lenBuf[:])
+	if length == 0 {
+		return -1, nil, nil // keep-alive
+	}
+	if length > maxMessageSize {
+		return 0, nil, fmt.Errorf("[!] Peer sent an oversized message (%d bytes)\n", length)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(c, msg); err != nil {
+		return 0, nil, fmt.Errorf("[!] Could not read message body from peer\n%w", err)
+	}
+	return int8(msg[0]), msg[1:], nil
+}
+
+// WriteMessage sends one length-prefixed message to the peer
+func (c *Conn) WriteMessage(id int8, payload []byte) error {
+	length := uint32(len(payload) + 1)
+	buf := make([]byte, 4+length)
+	binary.BigEndian.PutUint32(buf[0:4], length)
+	buf[4] = byte(id)
+	copy(buf[5:], payload)
+	if _, err := c.Write(buf); err != nil {
+		return fmt.Errorf("[!] Could not send message to peer\n%w", err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/eduardo-antunes/torrent-go/internal/benc"
+)
+
+// FileClient is a ClientImpl that stores each of a torrent's files directly
+// on disk under Root, mirroring the torrent's own directory structure
+type FileClient struct {
+	Root string
+}
+
+// NewFileClient returns a FileClient rooted at root, creating it if it
+// doesn't already exist
+func NewFileClient(root string) (*FileClient, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("[!] Could not create storage root %s\n%w", root, err)
+	}
+	return &FileClient{Root: root}, nil
+}
+
+// fileSpan is one file's placement within the concatenation of all of a
+// torrent's files, the same addressing scheme used by Info.Piece
+type fileSpan struct {
+	file   *os.File
+	offset uint64
+	length uint64
+}
+
+type fileTorrent struct {
+	info  *benc.Info
+	spans []fileSpan
+
+	mu        sync.Mutex
+	completed []bool
+}
+
+// OpenTorrent creates (if needed) and opens every file of t under c.Root,
+// truncated to its final length, ready for piece-addressed reads and writes
+func (c *FileClient) OpenTorrent(t *benc.Torrent) (TorrentImpl, error) {
+	files := t.Files()
+	spans := make([]fileSpan, len(files))
+	var offset uint64
+	for i, f := range files {
+		path := filepath.Join(append([]string{c.Root}, filePath(t, f)...)...)
+		fh, err := openSized(path, f.Length)
+		if err != nil {
+			return nil, err
+		}
+		spans[i] = fileSpan{file: fh, offset: offset, length: f.Length}
+		offset += f.Length
+	}
+	return &fileTorrent{
+		info:      &t.Info,
+		spans:     spans,
+		completed: make([]bool, t.Info.NumPieces()),
+	}, nil
+}
+
+// Creates path, along with any missing parent directories, and truncates it
+// to length
+func openSized(path string, length uint64) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("[!] Could not create directory for %s\n%w", path, err)
+	}
+	fh, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Could not open file %s\n%w", path, err)
+	}
+	if err := fh.Truncate(int64(length)); err != nil {
+		fh.Close()
+		return nil, fmt.Errorf("[!] Could not size file %s\n%w", path, err)
+	}
+	return fh, nil
+}
+
+func (ft *fileTorrent) Piece(i int) (PieceImpl, error) {
+	if i < 0 || i >= ft.info.NumPieces() {
+		return nil, fmt.Errorf("[!] Piece index %d out of range\n", i)
+	}
+	p := ft.info.Piece(i)
+	return &filePiece{torrent: ft, index: i, offset: p.Offset, length: p.Length}, nil
+}
+
+func (ft *fileTorrent) Close() error {
+	for _, s := range ft.spans {
+		if err := s.file.Close(); err != nil {
+			return fmt.Errorf("[!] Could not close file\n%w", err)
+		}
+	}
+	return nil
+}
+
+type filePiece struct {
+	torrent *fileTorrent
+	index   int
+	offset  uint64
+	length  uint64
+}
+
+func (p *filePiece) ReadAt(b []byte, off int64) (int, error) { return p.rw(b, off, false) }
+
+func (p *filePiece) WriteAt(b []byte, off int64) (int, error) { return p.rw(b, off, true) }
+
+// rw dispatches a read or write of b at the piece-relative offset off to
+// whichever of the torrent's files it falls under, splitting it across a
+// file boundary if the piece straddles one
+func (p *filePiece) rw(b []byte, off int64, write bool) (int, error) {
+	if off < 0 || uint64(off)+uint64(len(b)) > p.length {
+		return 0, fmt.Errorf("[!] Piece access out of bounds\n")
+	}
+	global := p.offset + uint64(off)
+	remaining := b
+	var n int
+	for _, s := range p.torrent.spans {
+		if len(remaining) == 0 {
+			break
+		}
+		spanEnd := s.offset + s.length
+		if global >= spanEnd {
+			continue // piece doesn't reach this file
+		}
+		chunk := remaining
+		if avail := spanEnd - global; uint64(len(chunk)) > avail {
+			chunk = chunk[:avail]
+		}
+		var m int
+		var err error
+		if write {
+			m, err = s.file.WriteAt(chunk, int64(global-s.offset))
+		} else {
+			m, err = s.file.ReadAt(chunk, int64(global-s.offset))
+		}
+		n += m
+		if err != nil {
+			return n, fmt.Errorf("[!] Piece I/O failed\n%w", err)
+		}
+		remaining = remaining[m:]
+		global += uint64(m)
+	}
+	return n, nil
+}
+
+func (p *filePiece) MarkComplete() error {
+	p.torrent.mu.Lock()
+	p.torrent.completed[p.index] = true
+	p.torrent.mu.Unlock()
+	return nil
+}
+
+func (p *filePiece) Completion() bool {
+	p.torrent.mu.Lock()
+	defer p.torrent.mu.Unlock()
+	return p.torrent.completed[p.index]
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eduardo-antunes/torrent-go/internal/benc"
+)
+
+// Builds a two-file torrent (12 and 8 bytes) with a 10-byte piece length, so
+// that its second piece straddles the boundary between the two files
+func buildStraddlingTorrent(t *testing.T) *benc.Torrent {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.bin"), bytes.Repeat([]byte("A"), 12), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.bin"), bytes.Repeat([]byte("B"), 8), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	b, err := benc.NewBuilder(dir)
+	if err != nil {
+		t.Fatalf("NewBuilder failed: %v", err)
+	}
+	b.PieceLength = 10
+	torrent, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	return torrent
+}
+
+// A BEP 3 multi-file torrent (info.files set) can legally contain a single
+// file entry; it must still be nested under a directory named after the
+// torrent, unlike a true single-file torrent (info.length set)
+func TestFileClientSingleEntryMultiFileTorrent(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "only.bin"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	b, err := benc.NewBuilder(srcDir)
+	if err != nil {
+		t.Fatalf("NewBuilder failed: %v", err)
+	}
+	torrent, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(torrent.Info.Files) != 1 {
+		t.Fatalf("expected a multi-file info dict with one entry, got Info.Files = %v", torrent.Info.Files)
+	}
+
+	c, err := NewFileClient(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileClient failed: %v", err)
+	}
+	ti, err := c.OpenTorrent(torrent)
+	if err != nil {
+		t.Fatalf("OpenTorrent failed: %v", err)
+	}
+	defer ti.Close()
+
+	nested := filepath.Join(c.Root, torrent.Info.Name, "only.bin")
+	if _, err := os.Stat(nested); err != nil {
+		t.Fatalf("expected file at %s, got: %v", nested, err)
+	}
+	if _, err := os.Stat(filepath.Join(c.Root, "only.bin")); err == nil {
+		t.Fatalf("file was written directly under Root, want it nested under %s", torrent.Info.Name)
+	}
+}
+
+// Builds a v2-only torrent whose file tree has a single leaf nested one
+// level under a directory distinct from the torrent's own name, and checks
+// that it's laid out nested, not mistaken for true single-file mode
+func buildV2NestedTorrent(t *testing.T) *benc.Torrent {
+	t.Helper()
+	root := bytes.Repeat([]byte{0xCD}, 32)
+	info := map[string]any{
+		"file tree": map[string]any{
+			"sub": map[string]any{
+				"a.bin": map[string]any{
+					"": map[string]any{"length": 5, "pieces root": root},
+				},
+			},
+		},
+		"meta version": 2,
+		"name":         "container",
+		"piece length": 16384,
+	}
+	enc, err := benc.EncodeDict(map[string]any{"announce": "test", "info": info})
+	if err != nil {
+		t.Fatalf("EncodeDict failed: %v", err)
+	}
+	torrent, err := benc.ParseTorrent(enc)
+	if err != nil {
+		t.Fatalf("ParseTorrent failed: %v", err)
+	}
+	return torrent
+}
+
+func TestFileClientV2NestedSingleFile(t *testing.T) {
+	torrent := buildV2NestedTorrent(t)
+	files := torrent.Files()
+	if len(files) != 1 || files[0].Path[len(files[0].Path)-1] != "a.bin" {
+		t.Fatalf("Files() = %v, want a single nested a.bin entry", files)
+	}
+
+	c, err := NewFileClient(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileClient failed: %v", err)
+	}
+	ti, err := c.OpenTorrent(torrent)
+	if err != nil {
+		t.Fatalf("OpenTorrent failed: %v", err)
+	}
+	defer ti.Close()
+
+	nested := filepath.Join(c.Root, "container", "sub", "a.bin")
+	if _, err := os.Stat(nested); err != nil {
+		t.Fatalf("expected file at %s, got: %v", nested, err)
+	}
+}
+
+func TestFileClientPieceAcrossFiles(t *testing.T) {
+	torrent := buildStraddlingTorrent(t)
+
+	c, err := NewFileClient(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileClient failed: %v", err)
+	}
+	ti, err := c.OpenTorrent(torrent)
+	if err != nil {
+		t.Fatalf("OpenTorrent failed: %v", err)
+	}
+	defer ti.Close()
+
+	// piece 1 covers bytes [10, 20): the last 2 bytes of a.bin and the first
+	// 8 bytes of b.bin
+	piece, err := ti.Piece(1)
+	if err != nil {
+		t.Fatalf("Piece(1) failed: %v", err)
+	}
+	want := []byte("AABBBBBBBB")
+	if _, err := piece.WriteAt(want, 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	got := make([]byte, len(want))
+	if _, err := piece.ReadAt(got, 0); err != nil {
+		t.Fatalf("ReadAt failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("ReadAt = %q, want %q", got, want)
+	}
+
+	if piece.Completion() {
+		t.Fatalf("Completion() = true before MarkComplete")
+	}
+	if err := piece.MarkComplete(); err != nil {
+		t.Fatalf("MarkComplete failed: %v", err)
+	}
+	if !piece.Completion() {
+		t.Fatalf("Completion() = false after MarkComplete")
+	}
+
+	// writing back across the same boundary should land in both files
+	if _, err := piece.WriteAt([]byte("XXYYYYYYYY"), 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	dir := filepath.Join(c.Root, torrent.Info.Name)
+	raw, err := os.ReadFile(filepath.Join(dir, "a.bin"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(raw[10:12]) != "XX" {
+		t.Fatalf("a.bin tail = %q, want %q", raw[10:12], "XX")
+	}
+	raw, err = os.ReadFile(filepath.Join(dir, "b.bin"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(raw) != "YYYYYYYY" {
+		t.Fatalf("b.bin = %q, want %q", raw, "YYYYYYYY")
+	}
+}
@@ -0,0 +1,176 @@
+//go:build unix
+
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/eduardo-antunes/torrent-go/internal/benc"
+)
+
+// MmapClient is a ClientImpl like FileClient, except that each file is
+// mapped into memory instead of being read and written through ordinary
+// file descriptor calls. This pays off for read-heavy workloads, such as
+// seeding, where repeated piece reads are then served straight out of the
+// kernel page cache instead of going through a read syscall each time
+type MmapClient struct {
+	Root string
+}
+
+// NewMmapClient returns an MmapClient rooted at root, creating it if it
+// doesn't already exist
+func NewMmapClient(root string) (*MmapClient, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("[!] Could not create storage root %s\n%w", root, err)
+	}
+	return &MmapClient{Root: root}, nil
+}
+
+// mmapSpan is one file's placement within the concatenation of all of a
+// torrent's files, the same addressing scheme used by Info.Piece
+type mmapSpan struct {
+	file   *os.File
+	data   []byte // nil for an empty file, which can't be mapped
+	offset uint64
+	length uint64
+}
+
+type mmapTorrent struct {
+	info  *benc.Info
+	spans []mmapSpan
+
+	mu        sync.Mutex
+	completed []bool
+}
+
+// OpenTorrent creates (if needed), sizes and maps every file of t under
+// c.Root, ready for piece-addressed reads and writes
+func (c *MmapClient) OpenTorrent(t *benc.Torrent) (TorrentImpl, error) {
+	files := t.Files()
+	spans := make([]mmapSpan, len(files))
+	var offset uint64
+	for i, f := range files {
+		path := filepath.Join(append([]string{c.Root}, filePath(t, f)...)...)
+		fh, err := openSized(path, f.Length)
+		if err != nil {
+			return nil, err
+		}
+		var data []byte
+		if f.Length > 0 {
+			data, err = syscall.Mmap(int(fh.Fd()), 0, int(f.Length),
+				syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+			if err != nil {
+				fh.Close()
+				return nil, fmt.Errorf("[!] Could not mmap file %s\n%w", path, err)
+			}
+		}
+		spans[i] = mmapSpan{file: fh, data: data, offset: offset, length: f.Length}
+		offset += f.Length
+	}
+	return &mmapTorrent{
+		info:      &t.Info,
+		spans:     spans,
+		completed: make([]bool, t.Info.NumPieces()),
+	}, nil
+}
+
+func (ft *mmapTorrent) Piece(i int) (PieceImpl, error) {
+	if i < 0 || i >= ft.info.NumPieces() {
+		return nil, fmt.Errorf("[!] Piece index %d out of range\n", i)
+	}
+	p := ft.info.Piece(i)
+	return &mmapPiece{torrent: ft, index: i, offset: p.Offset, length: p.Length}, nil
+}
+
+func (ft *mmapTorrent) Close() error {
+	for _, s := range ft.spans {
+		if s.data != nil {
+			if err := syscall.Munmap(s.data); err != nil {
+				return fmt.Errorf("[!] Could not unmap file\n%w", err)
+			}
+		}
+		if err := s.file.Close(); err != nil {
+			return fmt.Errorf("[!] Could not close file\n%w", err)
+		}
+	}
+	return nil
+}
+
+type mmapPiece struct {
+	torrent *mmapTorrent
+	index   int
+	offset  uint64
+	length  uint64
+}
+
+func (p *mmapPiece) ReadAt(b []byte, off int64) (int, error) { return p.rw(b, off, false) }
+
+func (p *mmapPiece) WriteAt(b []byte, off int64) (int, error) { return p.rw(b, off, true) }
+
+// rw copies a read or write of b at the piece-relative offset off to
+// whichever of the torrent's mapped files it falls under, splitting it
+// across a file boundary if the piece straddles one
+func (p *mmapPiece) rw(b []byte, off int64, write bool) (int, error) {
+	if off < 0 || uint64(off)+uint64(len(b)) > p.length {
+		return 0, fmt.Errorf("[!] Piece access out of bounds\n")
+	}
+	global := p.offset + uint64(off)
+	remaining := b
+	var n int
+	for _, s := range p.torrent.spans {
+		if len(remaining) == 0 {
+			break
+		}
+		spanEnd := s.offset + s.length
+		if global >= spanEnd {
+			continue // piece doesn't reach this file
+		}
+		chunk := remaining
+		if avail := spanEnd - global; uint64(len(chunk)) > avail {
+			chunk = chunk[:avail]
+		}
+		local := global - s.offset
+		if write {
+			copy(s.data[local:], chunk)
+		} else {
+			copy(chunk, s.data[local:])
+		}
+		n += len(chunk)
+		remaining = remaining[len(chunk):]
+		global += uint64(len(chunk))
+	}
+	return n, nil
+}
+
+func (p *mmapPiece) MarkComplete() error {
+	p.torrent.mu.Lock()
+	p.torrent.completed[p.index] = true
+	p.torrent.mu.Unlock()
+	return nil
+}
+
+func (p *mmapPiece) Completion() bool {
+	p.torrent.mu.Lock()
+	defer p.torrent.mu.Unlock()
+	return p.torrent.completed[p.index]
+}
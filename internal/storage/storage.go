@@ -0,0 +1,86 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Defines the storage interface trio through which piece data is laid out,
+// read, written and tracked for completion, independently of where it
+// actually lives. A backend only ever sees benc's already-parsed types
+// (piece length, the upverted file list, piece hashes), never bencode
+// itself, which is what lets third parties plug in their own ClientImpl
+// (S3, boltdb, in-memory, ...) without touching the rest of the module.
+package storage
+
+import (
+	"io"
+
+	"github.com/eduardo-antunes/torrent-go/internal/benc"
+)
+
+// ClientImpl is the entry point of a storage backend: given a parsed
+// torrent, it opens (creating it if necessary) the backend's representation
+// of that torrent's data
+type ClientImpl interface {
+	OpenTorrent(t *benc.Torrent) (TorrentImpl, error)
+}
+
+// TorrentImpl is implemented once per open torrent, handing out a PieceImpl
+// for each of the torrent's pieces
+type TorrentImpl interface {
+	// Piece returns the i-th piece, as numbered by t.Info.Piece
+	Piece(i int) (PieceImpl, error)
+	Close() error
+}
+
+// PieceImpl gives random access to a single piece's data, plus completion
+// bookkeeping so that callers such as a downloader can tell which pieces
+// still need to be fetched or re-verified
+type PieceImpl interface {
+	io.ReaderAt
+	io.WriterAt
+
+	// MarkComplete records that the piece's data is present and has passed
+	// its hash check
+	MarkComplete() error
+	// Completion reports whether MarkComplete has been called for the piece
+	Completion() bool
+}
+
+// filePath returns the on-disk path components for file f of torrent t,
+// relative to a backend's root. A multi-file torrent's files are nested
+// under a directory named after t.Info.Name, matching how BEP 3 describes
+// Name ("the suggested name to save the file (or directory) as") and how
+// other clients lay multi-file torrents out on disk; a single-file
+// torrent's one file already has t.Info.Name as its path.
+//
+// len(t.Files()) == 1 alone isn't enough to tell the two modes apart: a BEP
+// 3 multi-file torrent (info.files set) can legally contain just one file
+// entry and must still be nested, unlike true single-file mode (info.length
+// set, info.Files empty). v2-only torrents have no v1 Files list to check;
+// BEP 52's file tree doesn't distinguish the two modes structurally either,
+// but a true single-file tree's one entry is keyed by the file's own name,
+// which is also what Info.Name holds (there's no separate root folder to
+// name), whereas a nested file's leaf name generally differs from the
+// torrent's own Info.Name
+func filePath(t *benc.Torrent, f benc.FileInfo) []string {
+	singleFile := len(t.Info.Files) == 0
+	if t.IsV2Only() {
+		files := t.Files()
+		singleFile = len(files) == 1 && len(files[0].Path) == 1 && files[0].Path[0] == t.Info.Name
+	}
+	if singleFile {
+		return f.Path
+	}
+	return append([]string{t.Info.Name}, f.Path...)
+}
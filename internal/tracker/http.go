@@ -0,0 +1,63 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/eduardo-antunes/torrent-go/internal/benc"
+)
+
+// Encodes the request parameters into an HTTP query string
+func (req *Request) query() string {
+	return fmt.Sprintf("info_hash=%s&peer_id=%s&event=%s&port=%v&uploaded=%v"+
+		"&downloaded=%v&left=%v&compact=1&numwant=%v",
+		url.QueryEscape(string(req.InfoHash[:])), url.QueryEscape(string(req.PeerId[:])),
+		req.Event, req.Port, req.Uploaded, req.Downloaded, req.Left, req.NumWant)
+}
+
+// Announces to an HTTP(S) tracker with a plain GET request
+func announceHTTP(ctx context.Context, u *url.URL, req *Request) (*Response, error) {
+	u.RawQuery = req.query()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Could not build tracker request\n%w", err)
+	}
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("[!] HTTP tracker request failed\n%w", err)
+	}
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Could not read tracker response\n%w", err)
+	}
+	raw, err := benc.ParseTrackerResponse(body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		Interval:    raw.Interval,
+		MinInterval: raw.MinInterval,
+		TrackerId:   raw.TrackerId,
+		Peers:       raw.Peers,
+	}, nil
+}
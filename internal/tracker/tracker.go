@@ -0,0 +1,123 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// This package implements the client side of the BitTorrent tracker
+// protocols, dispatching by URL scheme: HTTP(S) trackers are contacted with a
+// plain GET request (the bencoded response is parsed by the benc package),
+// while UDP trackers are spoken to directly, as described by BEP 15.
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strings"
+
+	"github.com/eduardo-antunes/torrent-go/internal/benc"
+)
+
+// Parameters for a tracker announce request
+type Request struct {
+	InfoHash   [20]byte
+	PeerId     [20]byte
+	Event      string // one of started, stopped, completed or empty
+	Port       uint16
+	Uploaded   uint64
+	Downloaded uint64
+	Left       uint64
+	NumWant    int32 // negative requests the tracker's default
+}
+
+// The response to a tracker announce request, normalized across the HTTP and
+// UDP tracker protocols
+type Response struct {
+	Interval    int
+	MinInterval int
+	TrackerId   string
+	Peers       []benc.PeerAddr
+}
+
+// Create a request object corresponding to the initial, "started" announce
+// that is first sent to the tracker
+func NewRequest(infoHash [20]byte, left uint64, port uint16) *Request {
+	return &Request{
+		InfoHash: infoHash,
+		PeerId:   generatePeerId(),
+		Event:    "started",
+		Port:     port,
+		Left:     left,
+		NumWant:  -1,
+	}
+}
+
+// Announce sends an announce request to the trackers in list, which is
+// organized in tiers as described by BEP 12: tiers are tried in order, and
+// within a tier, the URLs are shuffled and tried in turn until one succeeds.
+// A tracker that answers successfully is promoted to the front of its tier,
+// so that it is preferred on the next announce.
+func Announce(ctx context.Context, list [][]string, req *Request) (*Response, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("[!] No trackers to announce to\n")
+	}
+	var lastErr error
+	for _, tier := range list {
+		shuffle(tier)
+		for i, rawUrl := range tier {
+			resp, err := announceOne(ctx, rawUrl, req)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			tier[0], tier[i] = tier[i], tier[0]
+			return resp, nil
+		}
+	}
+	return nil, fmt.Errorf("[!] All trackers failed\n%w", lastErr)
+}
+
+// Dispatches a single announce request by the target URL's scheme
+func announceOne(ctx context.Context, rawUrl string, req *Request) (*Response, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Invalid tracker URL %s\n%w", rawUrl, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return announceHTTP(ctx, u, req)
+	case "udp":
+		return announceUDP(ctx, u, req)
+	}
+	return nil, fmt.Errorf("[!] Unsupported tracker scheme %q\n", u.Scheme)
+}
+
+// Shuffles a tier in place, as recommended by BEP 12 to spread load across
+// equally-preferred trackers
+func shuffle(tier []string) {
+	rand.Shuffle(len(tier), func(i, j int) { tier[i], tier[j] = tier[j], tier[i] })
+}
+
+// Generates a semi-random peer ID for this computer
+func generatePeerId() [20]byte {
+	// Peer ID = client ID + random bytes
+	var id [20]byte
+	clientId := "-TG1000-"
+	n := copy(id[:], clientId)
+	for ; n < len(id); n++ {
+		id[n] = byte(rand.Int() & 0xFF)
+	}
+	return id
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2024 Eduardo Antunes dos Santos Vieira
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Implements the UDP tracker protocol, as described by BEP 15.
+
+package tracker
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/eduardo-antunes/torrent-go/internal/benc"
+)
+
+const (
+	udpProtocolMagic  = 0x41727101980
+	udpActionConnect  = 0
+	udpActionAnnounce = 1
+	udpActionError    = 3
+)
+
+// Base timeout for a UDP tracker exchange; BEP 15 specifies retransmission
+// with exponential backoff of 15 * 2^n seconds, up to n = 8
+const udpBaseTimeout = 15 * time.Second
+const udpMaxRetries = 8
+
+// Connection ids handed out by a UDP tracker are valid for one minute and are
+// meant to be reused across requests to the same tracker in that window
+type connIdEntry struct {
+	id        uint64
+	expiresAt time.Time
+}
+
+var connIdCache = struct {
+	sync.Mutex
+	byHost map[string]connIdEntry
+}{byHost: make(map[string]connIdEntry)}
+
+// Announces to a UDP tracker, following the connect/announce exchange
+// described by BEP 15
+func announceUDP(ctx context.Context, u *url.URL, req *Request) (*Response, error) {
+	conn, err := net.Dial("udp", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("[!] Could not reach UDP tracker %s\n%w", u.Host, err)
+	}
+	defer conn.Close()
+
+	connId, err := udpConnect(ctx, conn, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	return udpAnnounce(ctx, conn, connId, req)
+}
+
+// Sends a connect request, returning the connection id to be used in the
+// subsequent announce request. Ids are cached per tracker host, as they
+// remain valid for one minute
+func udpConnect(ctx context.Context, conn net.Conn, host string) (uint64, error) {
+	connIdCache.Lock()
+	if e, ok := connIdCache.byHost[host]; ok && time.Now().Before(e.expiresAt) {
+		connIdCache.Unlock()
+		return e.id, nil
+	}
+	connIdCache.Unlock()
+
+	txId := rand.Uint32()
+	pkt := make([]byte, 16)
+	binary.BigEndian.PutUint64(pkt[0:8], udpProtocolMagic)
+	binary.BigEndian.PutUint32(pkt[8:12], udpActionConnect)
+	binary.BigEndian.PutUint32(pkt[12:16], txId)
+
+	buf := make([]byte, 16)
+	size, err := udpRoundTrip(ctx, conn, pkt, buf, 16)
+	if err != nil {
+		return 0, err
+	}
+	buf = buf[:size]
+	if got := binary.BigEndian.Uint32(buf[4:8]); got != txId {
+		return 0, fmt.Errorf("[!] UDP tracker transaction id mismatch\n")
+	}
+	if action := binary.BigEndian.Uint32(buf[0:4]); action != udpActionConnect {
+		return 0, fmt.Errorf("[!] UDP tracker sent unexpected action %d\n", action)
+	}
+	connId := binary.BigEndian.Uint64(buf[8:16])
+
+	connIdCache.Lock()
+	connIdCache.byHost[host] = connIdEntry{id: connId, expiresAt: time.Now().Add(time.Minute)}
+	connIdCache.Unlock()
+	return connId, nil
+}
+
+// Sends an announce request over an already connected UDP socket
+func udpAnnounce(ctx context.Context, conn net.Conn, connId uint64, req *Request) (*Response, error) {
+	txId := rand.Uint32()
+	pkt := make([]byte, 98)
+	binary.BigEndian.PutUint64(pkt[0:8], connId)
+	binary.BigEndian.PutUint32(pkt[8:12], udpActionAnnounce)
+	binary.BigEndian.PutUint32(pkt[12:16], txId)
+	copy(pkt[16:36], req.InfoHash[:])
+	copy(pkt[36:56], req.PeerId[:])
+	binary.BigEndian.PutUint64(pkt[56:64], req.Downloaded)
+	binary.BigEndian.PutUint64(pkt[64:72], req.Left)
+	binary.BigEndian.PutUint64(pkt[72:80], req.Uploaded)
+	binary.BigEndian.PutUint32(pkt[80:84], udpEventCode(req.Event))
+	binary.BigEndian.PutUint32(pkt[84:88], 0) // IP; 0 lets the tracker use the sender's
+	binary.BigEndian.PutUint32(pkt[88:92], rand.Uint32())
+	numWant := req.NumWant
+	if numWant == 0 {
+		numWant = -1
+	}
+	binary.BigEndian.PutUint32(pkt[92:96], uint32(numWant))
+	binary.BigEndian.PutUint16(pkt[96:98], req.Port)
+
+	// 20 bytes of header plus room for a generous number of compact peers
+	buf := make([]byte, 20+6*200)
+	size, err := udpRoundTrip(ctx, conn, pkt, buf, 20)
+	if err != nil {
+		return nil, err
+	}
+	buf = buf[:size]
+	if got := binary.BigEndian.Uint32(buf[4:8]); got != txId {
+		return nil, fmt.Errorf("[!] UDP tracker transaction id mismatch\n")
+	}
+	switch action := binary.BigEndian.Uint32(buf[0:4]); action {
+	case udpActionError:
+		return nil, fmt.Errorf("[!] UDP tracker error: %s\n", buf[8:])
+	case udpActionAnnounce:
+		// the rest of the function handles this case
+	default:
+		return nil, fmt.Errorf("[!] UDP tracker sent unexpected action %d\n", action)
+	}
+	peers, err := benc.DecodeCompactPeers(buf[20:])
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		Interval: int(binary.BigEndian.Uint32(buf[8:12])),
+		Peers:    peers,
+	}, nil
+}
+
+func udpEventCode(event string) uint32 {
+	switch event {
+	case "completed":
+		return 1
+	case "started":
+		return 2
+	case "stopped":
+		return 3
+	}
+	return 0
+}
+
+// Sends pkt over conn and waits for a response of at least minLen bytes into
+// buf, retrying with the backoff schedule from BEP 15 (15s, 30s, 60s, ...,
+// doubling up to n = 8) until it gets one or runs out of attempts
+func udpRoundTrip(ctx context.Context, conn net.Conn, pkt, buf []byte, minLen int) (int, error) {
+	var lastErr error
+	for n := 0; n <= udpMaxRetries; n++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if _, err := conn.Write(pkt); err != nil {
+			return 0, fmt.Errorf("[!] Could not write to UDP tracker\n%w", err)
+		}
+		timeout := udpBaseTimeout * time.Duration(uint64(1)<<uint(n))
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		size, err := conn.Read(buf)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if size < minLen {
+			lastErr = fmt.Errorf("[!] UDP tracker response too short (%d bytes)\n", size)
+			continue
+		}
+		return size, nil
+	}
+	return 0, fmt.Errorf("[!] UDP tracker timed out after %d retries\n%w", udpMaxRetries, lastErr)
+}
@@ -17,30 +17,76 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"net/url"
 	"os"
+	"strings"
 
 	"github.com/eduardo-antunes/torrent-go/internal/benc"
+	"github.com/eduardo-antunes/torrent-go/internal/peer"
+	"github.com/eduardo-antunes/torrent-go/internal/tracker"
 )
 
-
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Printf("usage: %s <file.torrent>\n", os.Args[0])
+		fmt.Printf("usage: %s <file.torrent | magnet URI>\n", os.Args[0])
 		return
 	}
-	contents, err := os.ReadFile(os.Args[1])
+	torrent, err := load(os.Args[1])
 	if err != nil {
-		fmt.Printf("Could not open torrent file %s\n", os.Args[1])
+		fmt.Println(err)
 		return
 	}
-	torrent, err := benc.ParseMetaInfo(contents)
+
+	list := torrent.AnnounceList
+	if len(list) == 0 {
+		list = [][]string{{torrent.Announce}}
+	}
+	req := tracker.NewRequest(torrent.InfoHash, uint64(torrent.Info.TotalLength()), 6881)
+	resp, err := tracker.Announce(context.Background(), list, req)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-    announceUrl, _ := url.Parse(torrent.Announce)
-    query := NewTrackerQuery(string(torrent.InfoHash[:]), torrent.Info.Length, 6881)
-    TrackerAnnounce(announceUrl, query)
+	fmt.Printf("Tracker responded with %d peers\n", len(resp.Peers))
+
+	if !torrent.HasInfo() {
+		// a magnet link only carries the infohash; the info dict itself
+		// still has to be fetched from peers, as described by BEP 9
+		torrent, err = peer.FetchInfoFromPeers(context.Background(), torrent, resp.Peers)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Fetched info dict for %s from peers\n", torrent.Info.Name)
+	}
+}
+
+// Loads a Torrent either from a .torrent file or from a magnet URI,
+// dispatching on the latter's distinctive scheme. A magnet URI only ever
+// carries a v1 infohash and a handful of trackers; its info dict is left
+// unparsed (Torrent.HasInfo reports false), to be filled in later by
+// peer.FetchInfoFromPeers
+func load(arg string) (*benc.Torrent, error) {
+	if strings.HasPrefix(arg, "magnet:") {
+		magnet, err := benc.ParseMagnet(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !magnet.HasV1 {
+			return nil, fmt.Errorf("[!] Magnet URI has no v1 infohash\n")
+		}
+		torrent := &benc.Torrent{InfoHash: magnet.InfoHash}
+		torrent.Info.Name = magnet.DisplayName
+		if len(magnet.Trackers) > 0 {
+			torrent.Announce = magnet.Trackers[0]
+			torrent.AnnounceList = [][]string{magnet.Trackers}
+		}
+		return torrent, nil
+	}
+	contents, err := os.ReadFile(arg)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open torrent file %s", arg)
+	}
+	return benc.ParseTorrent(contents)
 }